@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// actionCost is the energy charged for an action that wins arbitration,
+// unless ctx.ActionCosts sets a cost for it.
+const actionCost = 0.8
+
+// actionCostOf returns the energy an action costs when it fires.
+func actionCostOf(ctx *Context, action string) float64 {
+	if c, ok := ctx.ActionCosts[action]; ok {
+		return c
+	}
+	return actionCost
+}
+
+// arbitrateActions selects which K_ACTION candidates fire this tick.
+//
+// Candidates are scored by their mass after inhibition. Energy is a cost per
+// candidate: an action that costs more than the energy left is scored by
+// mass*energy/cost, so when energy is short a cheap action can beat a
+// stronger but expensive one. The strongest candidates win, up to
+// MaxActionsPerTick, and at most one per mutually-exclusive group; a winner
+// that can no longer be paid for loses with reason "energy".
+// Ties are broken by action name, then by source block, so the outcome does
+// not depend on the order of ctx.Order.
+// Every losing candidate is recorded in ctx.ActionLosers with the reason.
+func arbitrateActions(ctx *Context, cands []Signal) []Signal {
+	ctx.ActionLosers = ctx.ActionLosers[:0]
+	if len(cands) == 0 {
+		return nil
+	}
+
+	scored := make([]Signal, len(cands))
+	for i, s := range cands {
+		if c := actionCostOf(ctx, s.Value); c > ctx.Energy {
+			s.Mass *= max(ctx.Energy, 0) / c
+		}
+		scored[i] = s
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		a, b := scored[i], scored[j]
+		if a.Mass != b.Mass {
+			return a.Mass > b.Mass
+		}
+		if a.Value != b.Value {
+			return a.Value < b.Value
+		}
+		return a.From < b.From
+	})
+
+	winners := make([]Signal, 0, 1)
+	groupWinner := make(map[string]string, 2)
+	fired := make(map[string]bool, 2)
+
+	for _, s := range scored {
+		if fired[s.Value] {
+			ctx.ActionLosers = append(ctx.ActionLosers, fmt.Sprintf("%s(duplicate from %s)", s.Value, s.From))
+			continue
+		}
+		if g := ctx.ActionGroups[s.Value]; g != "" {
+			if w, ok := groupWinner[g]; ok {
+				ctx.ActionLosers = append(ctx.ActionLosers, fmt.Sprintf("%s(group %s: lost to %s)", s.Value, g, w))
+				continue
+			}
+		}
+		cost := actionCostOf(ctx, s.Value)
+		if len(winners) > 0 && cost > ctx.Energy {
+			ctx.ActionLosers = append(ctx.ActionLosers,
+				fmt.Sprintf("%s(energy %.2f < cost %.2f)", s.Value, ctx.Energy, cost))
+			continue
+		}
+		if !ctx.AllowActionThisTick() {
+			ctx.ActionLosers = append(ctx.ActionLosers,
+				fmt.Sprintf("%s(rate limit %d/tick, mass=%.2f)", s.Value, ctx.MaxActionsPerTick, s.Mass))
+			continue
+		}
+
+		key := fmt.Sprintf("%d|%s|%s|%s", ctx.Tick, s.Kind, s.Value, s.From)
+		if ctx.CostedThisTick != nil && !ctx.CostedThisTick[key] {
+			ctx.CostedThisTick[key] = true
+			if ctx.Energy >= cost {
+				ctx.Energy -= cost
+				ctx.EnergySpentEpisode += cost
+				noteSpend(ctx, "ACTION", cost)
+			}
+		}
+
+		// A fired action becomes eligible for reward.
+		if s.From != "" {
+			ctx.Elig[s.From] = 1.0
+		}
+		if g := ctx.ActionGroups[s.Value]; g != "" {
+			groupWinner[g] = s.Value
+		}
+		fired[s.Value] = true
+		winners = append(winners, s)
+	}
+	return winners
+}
+
+// deliverActions passes the actions that won arbitration to every block's
+// React, as propagation did before arbitration moved after it. Whatever the
+// blocks emit in response is scheduled for the next tick rather than
+// propagated now, so a reaction cannot raise a new action candidate after
+// this tick's arbitration has settled.
+func deliverActions(ctx *Context, winners []Signal) {
+	for _, s := range winners {
+		for _, id := range ctx.Order {
+			if !mayReact(ctx, id) {
+				continue
+			}
+			out := ctx.Blocks[id].React(s, ctx)
+			chargeReaction(ctx, id, len(out))
+			for _, o := range out {
+				ctx.Schedule(o, 1)
+			}
+		}
+	}
+}
+
+// cmdActCost handles: actcost <action> <cost> | actcost <action> default | actcost
+func cmdActCost(ctx *Context, args []string) {
+	if len(args) == 2 {
+		if args[1] == "default" {
+			delete(ctx.ActionCosts, args[0])
+			fmt.Printf("Action cost %s = %.2f (default)\n", args[0], actionCost)
+			return
+		}
+		c, err := strconv.ParseFloat(args[1], 64)
+		if err != nil || c < 0 {
+			fmt.Println("usage: actcost <action> <cost>|default   (cost >= 0)")
+			return
+		}
+		ctx.ActionCosts[args[0]] = c
+		fmt.Printf("Action cost %s = %.2f\n", args[0], c)
+		return
+	}
+	if len(args) != 0 {
+		fmt.Println("usage: actcost <action> <cost>|default | actcost")
+		return
+	}
+	fmt.Printf("Action cost default = %.2f\n", actionCost)
+	for _, a := range sortedKeys(ctx.ActionCosts) {
+		fmt.Printf("Action cost %s = %.2f\n", a, ctx.ActionCosts[a])
+	}
+}
+
+// cmdActGroup handles: actgroup <group> <action...> | actgroup clear | actgroup
+func cmdActGroup(ctx *Context, args []string) {
+	if len(args) == 1 && args[0] == "clear" {
+		clearStringMap(ctx.ActionGroups)
+		fmt.Println("Action groups cleared")
+		return
+	}
+	if len(args) >= 2 {
+		for _, a := range args[1:] {
+			ctx.ActionGroups[a] = args[0]
+		}
+		fmt.Printf("Action group %s = %v\n", args[0], args[1:])
+		return
+	}
+
+	byGroup := make(map[string][]string)
+	for a, g := range ctx.ActionGroups {
+		byGroup[g] = append(byGroup[g], a)
+	}
+	if len(byGroup) == 0 {
+		fmt.Println("Action groups: (none)")
+		return
+	}
+	groups := make([]string, 0, len(byGroup))
+	for g := range byGroup {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	for _, g := range groups {
+		sort.Strings(byGroup[g])
+		fmt.Printf("Action group %s = %v\n", g, byGroup[g])
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestArbitrateActions(t *testing.T) {
+	act := func(v string, m float64, from string) Signal {
+		return Signal{Kind: K_ACTION, Value: v, Mass: m, From: from}
+	}
+	tests := []struct {
+		name   string
+		max    int
+		groups map[string]string
+		cands  []Signal
+		want   []string // winners as value/from
+		losers int
+	}{
+		{"none", 1, nil, nil, nil, 0},
+		{"strongest", 1, nil, []Signal{act("a", 0.5, "X"), act("b", 0.9, "Y")}, []string{"b/Y"}, 1},
+		{"tie by name", 1, nil, []Signal{act("b", 1, "X"), act("a", 1, "Y")}, []string{"a/Y"}, 1},
+		{"tie by source", 1, nil, []Signal{act("a", 1, "Y"), act("a", 1, "X")}, []string{"a/X"}, 1},
+		{"rate limit", 2, nil, []Signal{act("a", 0.5, "X"), act("b", 0.9, "Y"), act("c", 0.7, "Z")}, []string{"b/Y", "c/Z"}, 1},
+		{"duplicate", 2, nil, []Signal{act("a", 0.8, "Y"), act("a", 1, "X")}, []string{"a/X"}, 1},
+		{"group", 3, map[string]string{"a": "g", "b": "g"}, []Signal{act("a", 0.5, "X"), act("b", 0.9, "Y"), act("c", 0.3, "Z")}, []string{"b/Y", "c/Z"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			ctx.MaxActionsPerTick = tt.max
+			for a, g := range tt.groups {
+				ctx.ActionGroups[a] = g
+			}
+
+			var got []string
+			for _, s := range arbitrateActions(ctx, tt.cands) {
+				got = append(got, s.Value+"/"+s.From)
+				if ctx.Elig[s.From] != 1.0 {
+					t.Errorf("winner %s/%s not eligible for reward", s.Value, s.From)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("winners = %v, want %v", got, tt.want)
+			}
+			if len(ctx.ActionLosers) != tt.losers {
+				t.Errorf("losers = %v, want %d", ctx.ActionLosers, tt.losers)
+			}
+		})
+	}
+}
+
+func TestArbitrateEnergyCost(t *testing.T) {
+	act := func(v string, m float64) Signal {
+		return Signal{Kind: K_ACTION, Value: v, Mass: m, From: "ACTIONBLOCK:" + v}
+	}
+	tests := []struct {
+		name   string
+		energy float64
+		max    int
+		want   []string
+	}{
+		{"enough energy: strongest", 10, 1, []string{"jump"}},
+		{"short of energy: cheapest", 0.5, 1, []string{"step"}},
+		{"second winner unaffordable", 2.3, 2, []string{"jump"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			ctx.Energy = tt.energy
+			ctx.MaxActionsPerTick = tt.max
+			ctx.CostedThisTick = make(map[string]bool)
+			ctx.ActionCosts["jump"] = 2
+			ctx.ActionCosts["step"] = 0.5
+
+			var got []string
+			for _, s := range arbitrateActions(ctx, []Signal{act("jump", 1), act("step", 0.6)}) {
+				got = append(got, s.Value)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("winners = %v, want %v (losers %v)", got, tt.want, ctx.ActionLosers)
+			}
+		})
+	}
+}
+
+// echoBlock answers a fired action with a note.
+type echoBlock struct{}
+
+func (b *echoBlock) ID() string { return "ECHO" }
+
+func (b *echoBlock) Clone() Block { c := *b; return &c }
+
+func (b *echoBlock) React(s Signal, ctx *Context) []Signal {
+	if s.Kind == K_ACTION {
+		return []Signal{{Kind: K_NOTE, Value: "saw " + s.Value, Mass: 1, From: b.ID()}}
+	}
+	return nil
+}
+
+func (b *echoBlock) Tick(ctx *Context) []Signal { return nil }
+
+func TestFiredActionsReachBlocks(t *testing.T) {
+	ctx := NewContext()
+	ctx.AddBlock(&echoBlock{})
+	deliverActions(ctx, []Signal{{Kind: K_ACTION, Value: "left", Mass: 1}})
+
+	ctx.Tick++
+	got := deliverDue(ctx)
+	if len(got) != 1 || got[0].Value != "saw left" {
+		t.Errorf("delivered next tick = %v, want the echo of the action", got)
+	}
+}
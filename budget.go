@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// learnedPrefixes are the block types that memory budgets and forgetting act on.
+var learnedPrefixes = []string{"COACT:", "SEQ:", "COMPOSE:", "GAP:", "RHYTHM:"}
+
+// structOfID returns the structure name of a learned block, or "".
+func structOfID(id string) string {
+	for _, p := range learnedPrefixes {
+		if strings.HasPrefix(id, p) {
+			return strings.TrimPrefix(id, p)
+		}
+	}
+	return ""
+}
+
+// dependentsOf lists the blocks that only work while st exists:
+// compose blocks built on st and action links attached to st.
+func dependentsOf(ctx *Context, st string) []string {
+	out := make([]string, 0, 2)
+	for _, id := range ctx.Order {
+		switch b := ctx.Blocks[id].(type) {
+		case *ComposeBlock:
+			if b.base == st {
+				out = append(out, id)
+			}
+		case *ActionBlock:
+			if b.targetStruct == st {
+				out = append(out, id)
+			}
+		}
+	}
+	return out
+}
+
+// Utility scores how much a learned block is worth keeping:
+// prediction contribution (confidence of its best prediction),
+// activation frequency, transition mass, recency and dependents.
+func Utility(ctx *Context, id string) float64 {
+	st := structOfID(id)
+	if st == "" {
+		return math.Inf(1)
+	}
+	u := 0.0
+	if ctx.BestPred[st] != "" {
+		u += 2.0 * ctx.PredConf[st]
+	}
+	u += 0.5 * math.Log1p(float64(ctx.FireCount[id]))
+	mass := 0.0
+	for _, tok := range sortedKeys(ctx.TransCounts[st]) {
+		mass += ctx.TransCounts[st][tok]
+	}
+	u += 0.5 * mass
+	for _, dep := range dependentsOf(ctx, st) {
+		if strings.HasPrefix(dep, "COMPOSE:") {
+			u += 1.0
+		} else {
+			u += 0.25
+		}
+	}
+	if last, ok := ctx.BlockLastFire[id]; ok {
+		u += 1.0 / float64(1+ctx.Tick-last)
+	}
+	return u
+}
+
+// Rough per-entry sizes used by EstimateBytes.
+const (
+	bytesPerBlock = 160
+	bytesPerEntry = 48
+)
+
+// EstimateBytes approximates the memory held by blocks and the per-structure
+// maps that grow with them. It is an estimate for budgeting, not a measurement.
+func EstimateBytes(ctx *Context) int {
+	n := 0
+	for id := range ctx.Blocks {
+		n += bytesPerBlock + len(id)
+	}
+	for st, m := range ctx.TransCounts {
+		n += bytesPerEntry + len(st)
+		for tok := range m {
+			n += bytesPerEntry + len(tok)
+		}
+	}
+	n += bytesPerEntry * (len(ctx.BestPred) + len(ctx.PredConf) + len(ctx.BlockLastFire) + len(ctx.FireCount))
+	n += bytesPerEntry * (len(ctx.SeenPairs) + len(ctx.SeenSeq) + len(ctx.SeenComposes) + len(ctx.SeenGaps) + len(ctx.GapBase))
+	return n
+}
+
+func overBudget(ctx *Context) bool {
+	if ctx.MaxBlocks > 0 && len(ctx.Blocks) > ctx.MaxBlocks {
+		return true
+	}
+	return ctx.MaxBytes > 0 && EstimateBytes(ctx) > ctx.MaxBytes
+}
+
+// evictStructure removes a learned block together with its dependents
+// (compose blocks built on it, recursively, and its action links), the
+// structure's prediction state and its crystallization mark, so the
+// structure can be learned or injected again. It returns the removed block IDs.
+func evictStructure(ctx *Context, id string) []string {
+	st := structOfID(id)
+	if _, ok := ctx.Blocks[id]; !ok || st == "" {
+		return nil
+	}
+	removed := []string{id}
+	for _, dep := range dependentsOf(ctx, st) {
+		if strings.HasPrefix(dep, "COMPOSE:") {
+			removed = append(removed, evictStructure(ctx, dep)...)
+		} else {
+			removeBlock(ctx, dep)
+			removed = append(removed, dep)
+		}
+	}
+	resetEvidence(ctx, id)
+	removeBlock(ctx, id)
+	delete(ctx.TransCounts, st)
+	delete(ctx.BestPred, st)
+	delete(ctx.PredConf, st)
+	dropExceptions(ctx, st)
+	return removed
+}
+
+// resetEvidence clears the evidence entry that crystallized block id.
+func resetEvidence(ctx *Context, id string) {
+	switch b := ctx.Blocks[id].(type) {
+	case *CoActBlock:
+		delete(ctx.SeenPairs, pairKey(b.a, b.b))
+	case *SeqBlock:
+		delete(ctx.SeenSeq, b.a+">"+b.b)
+	case *ComposeBlock:
+		delete(ctx.SeenComposes, b.base+"||"+b.x)
+	case *GapSeqBlock:
+		delete(ctx.SeenGaps, gapKey(b.a, b.b, b.gap))
+	}
+}
+
+// enforceBudget evicts the lowest-utility learned structures until the
+// block and byte budgets hold again. Structures active in this or the
+// previous tick are evicted only when nothing else is left; pinned
+// structures are never evicted.
+func enforceBudget(ctx *Context) {
+	if !overBudget(ctx) {
+		return
+	}
+
+	type cand struct {
+		id     string
+		u      float64
+		active bool
+	}
+	cands := make([]cand, 0, 32)
+	for _, id := range sortedKeys(ctx.Blocks) {
+		st := structOfID(id)
+		if st == "" || ctx.Pinned[st] {
+			continue
+		}
+		cands = append(cands, cand{id: id, u: Utility(ctx, id), active: ctx.ThisStructSet[st] || ctx.PrevStructSet[st]})
+	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		if cands[i].active != cands[j].active {
+			return !cands[i].active
+		}
+		return cands[i].u < cands[j].u
+	})
+
+	for _, c := range cands {
+		if !overBudget(ctx) {
+			break
+		}
+		if _, ok := ctx.Blocks[c.id]; !ok {
+			continue // already gone as a dependent
+		}
+		removed := evictStructure(ctx, c.id)
+		ctx.Evictions += len(removed)
+		ctx.TrainEvents = append(ctx.TrainEvents,
+			fmt.Sprintf("--- EVICTED %s utility=%.2f (+%d dependents) blocks=%d bytes≈%d",
+				c.id, c.u, len(removed)-1, len(ctx.Blocks), EstimateBytes(ctx)))
+	}
+}
+
+// memoryUsage renders budget usage for the board.
+func memoryUsage(ctx *Context) string {
+	lim := func(v int) string {
+		if v <= 0 {
+			return "∞"
+		}
+		return strconv.Itoa(v)
+	}
+	return fmt.Sprintf("blocks=%d/%s bytes≈%d/%s evicted=%d",
+		len(ctx.Blocks), lim(ctx.MaxBlocks), EstimateBytes(ctx), lim(ctx.MaxBytes), ctx.Evictions)
+}
+
+// cmdBudget handles: budget [blocks N] [bytes N]   (0 = unlimited)
+func cmdBudget(ctx *Context, args []string) {
+	for i := 0; i+1 < len(args); i += 2 {
+		v, err := strconv.Atoi(args[i+1])
+		if err != nil || v < 0 {
+			fmt.Printf("budget: bad value %q\n", args[i+1])
+			return
+		}
+		switch args[i] {
+		case "blocks":
+			ctx.MaxBlocks = v
+		case "bytes":
+			ctx.MaxBytes = v
+		default:
+			fmt.Println("usage: budget [blocks N] [bytes N]   (0 = unlimited)")
+			return
+		}
+	}
+	if len(args)%2 == 1 {
+		fmt.Println("usage: budget [blocks N] [bytes N]   (0 = unlimited)")
+		return
+	}
+	enforceBudget(ctx)
+	for _, te := range ctx.TrainEvents {
+		if strings.HasPrefix(te, "--- EVICTED") {
+			cprintf(C_YELLOW, "           %s\n", te)
+		}
+	}
+	ctx.TrainEvents = ctx.TrainEvents[:0]
+
+	fmt.Printf("Memory: %s\n", memoryUsage(ctx))
+	ids := make([]string, 0, 16)
+	for _, id := range sortedKeys(ctx.Blocks) {
+		if structOfID(id) != "" {
+			ids = append(ids, id)
+		}
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return Utility(ctx, ids[i]) < Utility(ctx, ids[j]) })
+	if len(ids) > 6 {
+		ids = ids[:6]
+	}
+	for _, id := range ids {
+		fmt.Printf("           next to evict: %s utility=%.2f fired=%d\n", id, Utility(ctx, id), ctx.FireCount[id])
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEnforceBudgetEvictionOrder(t *testing.T) {
+	ctx := NewContext()
+	for st, mass := range map[string]float64{"[1-2]": 3, "[1-3]": 1, "[1-4]": 0} {
+		a, b, _ := parsePairMembers(st)
+		ctx.AddBlock(NewCoActBlock(a, b))
+		if mass > 0 {
+			ctx.TransCounts[st] = map[string]float64{"9": mass}
+		}
+	}
+	ctx.AddBlock(NewComposeBlock("[1-2]", "5"))
+	ctx.ThisStructSet["[1-4]"] = true // active: evicted last despite the lowest utility
+	ctx.MaxBlocks = 1
+
+	enforceBudget(ctx)
+
+	var evicted []string
+	for _, ev := range ctx.TrainEvents {
+		if f := strings.Fields(ev); len(f) > 2 && f[1] == "EVICTED" {
+			evicted = append(evicted, f[2])
+		}
+	}
+	want := []string{"COMPOSE:[[1-2]-5]", "COACT:[1-3]", "COACT:[1-2]"}
+	if !slices.Equal(evicted, want) {
+		t.Errorf("evicted %v, want %v", evicted, want)
+	}
+	if _, ok := ctx.Blocks["COACT:[1-4]"]; !ok || len(ctx.Blocks) != 1 {
+		t.Errorf("blocks left = %v, want only the active COACT:[1-4]", sortedKeys(ctx.Blocks))
+	}
+}
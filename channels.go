@@ -0,0 +1,221 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// chanOf returns the channel of a sensory token ("audio:beep" -> "audio").
+// Tokens without a channel prefix belong to the default channel "".
+func chanOf(tok string) string {
+	if i := strings.Index(tok, ":"); i > 0 {
+		return tok[:i]
+	}
+	return ""
+}
+
+// splitSimul splits an input item like "audio:beep+light:red" into the
+// tokens that arrive together in one tick.
+func splitSimul(item string) []string {
+	parts := strings.Split(item, "+")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func containsStr(xs []string, x string) bool {
+	for _, v := range xs {
+		if v == x {
+			return true
+		}
+	}
+	return false
+}
+
+// updateSensHistory records the sensory tokens of this tick, globally and per channel,
+// and derives the adjacent and simultaneous token pairs used by pair/seq learning.
+func updateSensHistory(ctx *Context, incoming []Signal) {
+	ctx.SensNow = ctx.SensNow[:0]
+	ctx.SeqPairsNow = ctx.SeqPairsNow[:0]
+	ctx.SimulPairsNow = ctx.SimulPairsNow[:0]
+
+	for _, s := range incoming {
+		if s.Kind == K_SENS {
+			ctx.SensNow = append(ctx.SensNow, sensToken(ctx, s.Value))
+		}
+	}
+	if len(ctx.SensNow) == 0 {
+		return
+	}
+
+	ctx.PrevSens = ctx.LastSens
+	ctx.LastSens = ctx.SensNow[len(ctx.SensNow)-1]
+
+	lastInChan := make(map[string]string, 2)
+	for _, tok := range ctx.SensNow {
+		lastInChan[chanOf(tok)] = tok
+	}
+	chans := make([]string, 0, len(lastInChan))
+	for ch, tok := range lastInChan {
+		ctx.PrevByChan[ch] = ctx.LastByChan[ch]
+		ctx.LastByChan[ch] = tok
+		ctx.ChanTick[ch] = ctx.Tick
+		chans = append(chans, ch)
+	}
+	sort.Strings(chans)
+
+	addSeq := func(a, b string) {
+		if a == "" || b == "" || a == b {
+			return
+		}
+		for _, p := range ctx.SeqPairsNow {
+			if p[0] == a && p[1] == b {
+				return
+			}
+		}
+		ctx.SeqPairsNow = append(ctx.SeqPairsNow, [2]string{a, b})
+	}
+	addSeq(ctx.PrevSens, ctx.LastSens)
+	for _, ch := range chans {
+		addSeq(ctx.PrevByChan[ch], ctx.LastByChan[ch])
+	}
+
+	for i := 0; i < len(ctx.SensNow); i++ {
+		for j := i + 1; j < len(ctx.SensNow); j++ {
+			a, b := ctx.SensNow[i], ctx.SensNow[j]
+			if a != b && chanOf(a) != chanOf(b) {
+				ctx.SimulPairsNow = append(ctx.SimulPairsNow, [2]string{a, b})
+			}
+		}
+	}
+}
+
+// sensPairsNow returns the ordered (previous, latest) token pairs of this tick:
+// the global stream plus every channel that received input.
+func sensPairsNow(ctx *Context) [][2]string { return ctx.SeqPairsNow }
+
+// simulPairsNow returns cross-channel token pairs that arrived in the same tick.
+func simulPairsNow(ctx *Context) [][2]string { return ctx.SimulPairsNow }
+
+// coActiveNow reports whether tokens a and b are co-active this tick, as seen
+// from the activation of tok: either adjacent (ending in tok) or simultaneous
+// on different channels (reported once, on the later token).
+func coActiveNow(ctx *Context, a, b, tok string) bool {
+	match := func(p [2]string) bool {
+		return p[1] == tok && ((p[0] == a && p[1] == b) || (p[0] == b && p[1] == a))
+	}
+	for _, p := range ctx.SeqPairsNow {
+		if match(p) {
+			return true
+		}
+	}
+	for _, p := range ctx.SimulPairsNow {
+		if match(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// armChannelExpectations arms one expectation per channel in multi-channel runs.
+// The winner keeps its expectation; every other channel gets the prediction of
+// the strongest active structure that expects a token on it.
+func armChannelExpectations(ctx *Context, winner string) {
+	if len(ctx.LastByChan) < 2 {
+		return
+	}
+
+	covered := make(map[string]bool, len(ctx.LastByChan))
+	for _, tok := range ctx.ThisExpect {
+		covered[chanOf(tok)] = true
+	}
+
+	cands := make([]string, 0, len(ctx.ThisStructMass))
+	for st := range ctx.ThisStructMass {
+		if st != winner {
+			cands = append(cands, st)
+		}
+	}
+	sort.Slice(cands, func(i, j int) bool {
+		mi, mj := ctx.ThisStructMass[cands[i]], ctx.ThisStructMass[cands[j]]
+		if mi != mj {
+			return mi > mj
+		}
+		return preferStructName(cands[i], cands[j])
+	})
+
+	for _, st := range cands {
+		pred := ctx.BestPred[st]
+		if pred == "" || predInhibited(ctx, st, pred) {
+			continue
+		}
+		ch := chanOf(pred)
+		if covered[ch] {
+			continue
+		}
+		ctx.ThisExpect[st] = pred
+		covered[ch] = true
+	}
+}
+
+// splitErrStruct splits an ERR value "st:pred->actual" after the structure name.
+// Structure names may contain ':' (channel tokens), so brackets are matched.
+func splitErrStruct(ev string) (st, rest string, ok bool) {
+	if ev == "" {
+		return "", "", false
+	}
+	if ev[0] == '[' || ev[0] == '(' {
+		depth := 0
+		for i := 0; i < len(ev); i++ {
+			switch ev[i] {
+			case '[', '(':
+				depth++
+			case ']', ')':
+				depth--
+			}
+			if depth == 0 {
+				if i+1 < len(ev) && ev[i+1] == ':' {
+					return ev[:i+1], ev[i+2:], true
+				}
+				return "", "", false
+			}
+		}
+		return "", "", false
+	}
+	i := strings.Index(ev, ":")
+	if i <= 0 {
+		return "", "", false
+	}
+	return ev[:i], ev[i+1:], true
+}
+
+// channelErrors lists per-channel error counts for the board.
+func channelErrors(ctx *Context) []string {
+	if len(ctx.LastByChan) < 2 && len(ctx.ChanErrs) < 2 {
+		return nil
+	}
+	chans := make([]string, 0, len(ctx.LastByChan))
+	for ch := range ctx.LastByChan {
+		chans = append(chans, ch)
+	}
+	for ch := range ctx.ChanErrs {
+		if _, ok := ctx.LastByChan[ch]; !ok {
+			chans = append(chans, ch)
+		}
+	}
+	sort.Strings(chans)
+	out := make([]string, 0, len(chans))
+	for _, ch := range chans {
+		name := ch
+		if name == "" {
+			name = "default"
+		}
+		out = append(out, name+":"+strconv.Itoa(ctx.ChanErrs[ch]))
+	}
+	return out
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestSplitErrStruct(t *testing.T) {
+	tests := []struct {
+		ev       string
+		st, rest string
+		ok       bool
+	}{
+		{"[1-2]:3->4", "[1-2]", "3->4", true},
+		{"(1>2):3->4", "(1>2)", "3->4", true},
+		{"[[1-2]-3]:4->5", "[[1-2]-3]", "4->5", true},
+		{"[audio:beep-light:red]:audio:beep->audio:buzz", "[audio:beep-light:red]", "audio:beep->audio:buzz", true},
+		{"(audio:a>audio:b):audio:c->_", "(audio:a>audio:b)", "audio:c->_", true},
+		{"~2/4:2->3", "~2/4", "2->3", true},
+		{"[1-2]3->4", "", "", false},
+		{"[1-2", "", "", false},
+		{":3->4", "", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		st, rest, ok := splitErrStruct(tt.ev)
+		if st != tt.st || rest != tt.rest || ok != tt.ok {
+			t.Errorf("splitErrStruct(%q) = %q, %q, %v; want %q, %q, %v", tt.ev, st, rest, ok, tt.st, tt.rest, tt.ok)
+		}
+	}
+}
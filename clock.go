@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Clock paces clocked runs. RealClock waits for wall-clock time;
+// SimClock only advances a virtual time, so runs are as fast as the CPU allows.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type RealClock struct{}
+
+func (RealClock) Now() time.Time        { return time.Now() }
+func (RealClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+type SimClock struct {
+	T time.Time
+}
+
+func (c *SimClock) Now() time.Time        { return c.T }
+func (c *SimClock) Sleep(d time.Duration) { c.T = c.T.Add(d) }
+
+// ClockOptions configures RunClocked.
+type ClockOptions struct {
+	Interval time.Duration // time between ticks
+	Ticks    int           // number of ticks to run
+	Clock    Clock         // defaults to a SimClock
+}
+
+// ClockReport summarizes a clocked run.
+type ClockReport struct {
+	Ticks   int
+	Inputs  int
+	Idle    int
+	Structs []string
+	Errs    []string
+	Train   []string
+	Elapsed time.Duration
+}
+
+// RunIdleTick advances the field by one tick with no external input.
+// Every block still runs Tick(), so decay, regeneration, cooldowns and
+// pruning keep going. When SilenceToken is set, the silence is sensed as a
+// token of its own, so structures can learn and predict it.
+func RunIdleTick(ctx *Context) []Signal {
+	if ctx.SilenceToken == "" {
+		return RunTick(ctx, nil)
+	}
+	ensureSensor(ctx, ctx.SilenceToken)
+	return RunTick(ctx, []Signal{{Kind: K_SENS, Value: ctx.SilenceToken, Mass: 1.0, Time: ctx.Tick, From: "CLOCK"}})
+}
+
+// RunClocked advances the field on a fixed interval instead of on input.
+// next is polled once per tick; it returns an input item ("a" or "a+b") and
+// true when input is available, otherwise the tick runs idle. Tick i is due
+// at start+i*Interval, so time spent processing a tick is taken out of the
+// wait instead of accumulating as drift; a tick that overruns is followed
+// by the next one at once.
+func RunClocked(ctx *Context, next func() (string, bool), opt ClockOptions) ClockReport {
+	clk := opt.Clock
+	if clk == nil {
+		clk = &SimClock{}
+	}
+	start := clk.Now()
+	rep := ClockReport{}
+
+	for i := 0; i < opt.Ticks; i++ {
+		ctx.PredEvents = ctx.PredEvents[:0]
+		ctx.TrainEvents = ctx.TrainEvents[:0]
+
+		var out []Signal
+		if item, ok := next(); ok {
+			out = feedItem(ctx, item, "USER")
+			rep.Inputs++
+		} else {
+			out = RunIdleTick(ctx)
+			rep.Idle++
+		}
+		rep.Ticks++
+
+		for _, s := range out {
+			switch s.Kind {
+			case K_STRUCT:
+				rep.Structs = append(rep.Structs, s.Value)
+			case K_ERR:
+				rep.Errs = append(rep.Errs, s.Value)
+			}
+		}
+		rep.Train = append(rep.Train, ctx.TrainEvents...)
+
+		if d := start.Add(time.Duration(i+1) * opt.Interval).Sub(clk.Now()); d > 0 {
+			clk.Sleep(d)
+		}
+	}
+
+	rep.Elapsed = clk.Now().Sub(start)
+	return rep
+}
+
+// ChanSource adapts a channel of input items for RunClocked.
+// It never blocks: a tick without a pending item is idle.
+func ChanSource(ch <-chan string) func() (string, bool) {
+	return func() (string, bool) {
+		select {
+		case item, ok := <-ch:
+			return item, ok
+		default:
+			return "", false
+		}
+	}
+}
+
+// ScriptSource feeds one item per tick; "." marks a tick with no input.
+// Once the script is exhausted every tick is idle.
+func ScriptSource(items []string) func() (string, bool) {
+	i := 0
+	return func() (string, bool) {
+		if i >= len(items) {
+			return "", false
+		}
+		item := items[i]
+		i++
+		if item == "." {
+			return "", false
+		}
+		return item, true
+	}
+}
+
+// cmdClock handles: clock <ms> <ticks> [items...] | clock silence [<tok>|off]
+// ms=0 runs on simulated time; items are fed one per tick, "." is silence.
+func cmdClock(ctx *Context, args []string) {
+	if len(args) >= 1 && args[0] == "silence" {
+		cmdClockSilence(ctx, args[1:])
+		return
+	}
+	if len(args) < 2 {
+		fmt.Println("usage: clock <ms> <ticks> [items...]   (ms=0: simulated time, '.' = no input) | clock silence [<tok>|off]")
+		return
+	}
+	ms, err := strconv.Atoi(args[0])
+	if err != nil || ms < 0 {
+		fmt.Printf("clock: bad interval %q\n", args[0])
+		return
+	}
+	ticks, err := strconv.Atoi(args[1])
+	if err != nil || ticks <= 0 {
+		fmt.Printf("clock: bad tick count %q\n", args[1])
+		return
+	}
+
+	opt := ClockOptions{Interval: time.Duration(ms) * time.Millisecond, Ticks: ticks}
+	mode := "simulated"
+	if ms > 0 {
+		opt.Clock = RealClock{}
+		mode = "wall-clock"
+	}
+
+	cprintf(C_MAGENTA+C_BOLD, "CLOCK: %d ticks every %dms (%s) silence=%q\n", ticks, ms, mode, ctx.SilenceToken)
+	rep := RunClocked(ctx, ScriptSource(args[2:]), opt)
+
+	for _, te := range rep.Train {
+		cprintf(C_GREEN, "           %s\n", te)
+	}
+	if len(rep.Errs) > 0 {
+		cprintf(C_RED, "           ERRORS=%v\n", uniqueKeepOrder(rep.Errs))
+	}
+	cprintf(C_MAGENTA, "CLOCK SUMMARY: ticks=%d inputs=%d idle=%d structs=%s elapsed=%s t=%03d\n",
+		rep.Ticks, rep.Inputs, rep.Idle, strings.Join(uniqueSorted(rep.Structs), ","), rep.Elapsed, ctx.Tick)
+}
+
+// cmdClockSilence handles: clock silence [<tok>|off]
+// It sets the token sensed on idle ticks; off leaves idle ticks without input.
+func cmdClockSilence(ctx *Context, args []string) {
+	switch {
+	case len(args) == 0:
+	case len(args) == 1 && args[0] == "off":
+		ctx.SilenceToken = ""
+	case len(args) == 1 && args[0] != "." && !strings.Contains(args[0], "+"):
+		ctx.SilenceToken = args[0]
+	default:
+		fmt.Println("usage: clock silence [<tok>|off]   (tok may not be '.' or contain '+')")
+		return
+	}
+	if ctx.SilenceToken == "" {
+		fmt.Println("Clock silence = off (idle ticks carry no input)")
+		return
+	}
+	fmt.Printf("Clock silence = %q\n", ctx.SilenceToken)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// busyClock is a simulated clock on which every reading costs work, as if
+// each tick took that long to process.
+type busyClock struct {
+	t    time.Time
+	work time.Duration
+}
+
+func (c *busyClock) Now() time.Time        { c.t = c.t.Add(c.work); return c.t }
+func (c *busyClock) Sleep(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestRunClockedDoesNotDrift(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		work     time.Duration
+		want     time.Duration
+	}{
+		{"idle clock", 10 * time.Millisecond, 0, 100 * time.Millisecond},
+		{"work absorbed by the wait", 10 * time.Millisecond, 3 * time.Millisecond, 103 * time.Millisecond},
+		{"overrun", 10 * time.Millisecond, 15 * time.Millisecond, 165 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			clk := &busyClock{work: tt.work}
+			rep := RunClocked(ctx, ScriptSource(nil), ClockOptions{Interval: tt.interval, Ticks: 10, Clock: clk})
+			if rep.Elapsed != tt.want {
+				t.Errorf("elapsed = %v, want %v", rep.Elapsed, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunClockedFeedsScript(t *testing.T) {
+	ctx := NewContext()
+	rep := RunClocked(ctx, ScriptSource([]string{"1", ".", "2"}), ClockOptions{Interval: time.Second, Ticks: 5})
+	if rep.Ticks != 5 || rep.Inputs != 2 || rep.Idle != 3 {
+		t.Errorf("ticks=%d inputs=%d idle=%d, want 5 2 3", rep.Ticks, rep.Inputs, rep.Idle)
+	}
+	if rep.Elapsed != 5*time.Second || ctx.Tick != 5 {
+		t.Errorf("elapsed=%v tick=%d, want 5s and 5", rep.Elapsed, ctx.Tick)
+	}
+}
+
+func TestClockSilence(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-"}, "-"},
+		{[]string{"off"}, ""},
+		{[]string{"."}, "_"},
+		{[]string{"a+b"}, "_"},
+		{nil, "_"},
+	}
+	for _, tt := range tests {
+		ctx := NewContext()
+		cmdClock(ctx, append([]string{"silence"}, tt.args...))
+		if ctx.SilenceToken != tt.want {
+			t.Errorf("clock silence %v: token = %q, want %q", tt.args, ctx.SilenceToken, tt.want)
+		}
+
+		RunIdleTick(ctx)
+		_, sensed := ctx.Sensors[tt.want]
+		if sensed != (tt.want != "") || len(ctx.Sensors) > 1 {
+			t.Errorf("clock silence %v: sensors after an idle tick = %v", tt.args, sortedKeys(ctx.Sensors))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import "strings"
+
+// runCommand dispatches REPL commands that take arguments.
+// It reports whether the line was a command (and has been handled).
+func runCommand(ctx *Context, fields []string) bool {
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToLower(fields[0]) {
+	case "generate":
+		cmdGenerate(ctx, fields[1:])
+		return true
+	case "env":
+		cmdEnv(ctx, fields[1:])
+		return true
+	case "drive":
+		cmdDrive(ctx, fields[1:])
+		return true
+	case "actgroup":
+		cmdActGroup(ctx, fields[1:])
+		return true
+	case "actcost":
+		cmdActCost(ctx, fields[1:])
+		return true
+	case "horizon":
+		cmdHorizon(ctx, fields[1:])
+		return true
+	case "gap":
+		cmdGap(ctx, fields[1:])
+		return true
+	case "clock":
+		cmdClock(ctx, fields[1:])
+		return true
+	case "sleep":
+		cmdSleep(ctx, fields[1:])
+		return true
+	case "gen":
+		cmdGen(ctx, fields[1:])
+		return true
+	case "scenario":
+		cmdScenario(ctx, fields[1:])
+		return true
+	case "queue":
+		cmdQueue(ctx, fields[1:])
+		return true
+	case "digest":
+		cmdDigest(ctx, fields[1:])
+		return true
+	case "seed":
+		cmdSeed(ctx, fields[1:])
+		return true
+	case "stoch":
+		cmdStoch(ctx, fields[1:])
+		return true
+	case "journal":
+		cmdJournal(ctx, fields[1:])
+		return true
+	case "robust":
+		cmdRobust(ctx, fields[1:])
+		return true
+	case "budget":
+		cmdBudget(ctx, fields[1:])
+		return true
+	case "forget":
+		cmdForget(ctx, fields[1:])
+		return true
+	case "edit":
+		cmdEdit(ctx, fields[1:])
+		return true
+	case "decay":
+		cmdDecay(ctx, fields[1:])
+		return true
+	case "except":
+		cmdExcept(ctx, fields[1:])
+		return true
+	case "neg":
+		cmdNeg(ctx, fields[1:])
+		return true
+	case "kernel":
+		cmdKernel(ctx, fields[1:])
+		return true
+	case "energy":
+		cmdEnergy(ctx, fields[1:])
+		return true
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Transition weights and prediction confidence otherwise change only when a
+// structure is active. decayUnobserved lets them fade while it is not:
+// after DecayGrace idle ticks, weights and PredConf shrink every tick so
+// that they halve every TransHalfLife ticks. Pinned structures do not decay.
+
+// noteObserved stamps the structures active this tick.
+func noteObserved(ctx *Context) {
+	for st := range ctx.ThisStructSet {
+		ctx.LastObserved[st] = ctx.Tick
+	}
+}
+
+func decayUnobserved(ctx *Context) {
+	if ctx.TransHalfLife <= 0 {
+		return
+	}
+	f := math.Pow(0.5, 1.0/float64(ctx.TransHalfLife))
+
+	for _, st := range sortedKeys(ctx.TransCounts) {
+		last, ok := ctx.LastObserved[st]
+		if !ok {
+			// Never seen active since decay was enabled: start its grace now.
+			ctx.LastObserved[st] = ctx.Tick
+			continue
+		}
+		if ctx.Tick-last <= ctx.DecayGrace || ctx.Pinned[st] {
+			continue
+		}
+
+		m := ctx.TransCounts[st]
+		for _, tok := range sortedKeys(m) {
+			m[tok] *= f
+			if m[tok] < 0.05 {
+				delete(m, tok)
+			}
+		}
+		if len(m) == 0 {
+			delete(ctx.TransCounts, st)
+			delete(ctx.BestPred, st)
+			delete(ctx.PredConf, st)
+			continue
+		}
+		if _, ok := m[ctx.BestPred[st]]; !ok {
+			refreshPrediction(ctx, st)
+			continue
+		}
+		ctx.PredConf[st] *= f
+		if ctx.PredConf[st] < 0.01 {
+			ctx.PredConf[st] = 0
+		}
+	}
+}
+
+// decayingPredictions lists the structures currently decaying, most idle first.
+func decayingPredictions(ctx *Context, n int) []string {
+	if ctx.TransHalfLife <= 0 {
+		return nil
+	}
+	sts := make([]string, 0, 8)
+	for _, st := range sortedStructsWithPred(ctx) {
+		if last, ok := ctx.LastObserved[st]; ok && ctx.Tick-last > ctx.DecayGrace && !ctx.Pinned[st] {
+			sts = append(sts, st)
+		}
+	}
+	sort.SliceStable(sts, func(i, j int) bool { return ctx.LastObserved[sts[i]] < ctx.LastObserved[sts[j]] })
+	if len(sts) > n {
+		sts = sts[:n]
+	}
+	out := make([]string, 0, len(sts))
+	for _, st := range sts {
+		out = append(out, fmt.Sprintf("%s⇒%s conf=%.2f idle=%d", st, ctx.BestPred[st], ctx.PredConf[st], ctx.Tick-ctx.LastObserved[st]))
+	}
+	return out
+}
+
+// cmdDecay handles: decay [halflife] [grace]   (halflife 0 = off)
+func cmdDecay(ctx *Context, args []string) {
+	if len(args) > 0 {
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v < 0 {
+			fmt.Printf("decay: bad half-life %q\n", args[0])
+			return
+		}
+		ctx.TransHalfLife = v
+	}
+	if len(args) > 1 {
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v < 0 {
+			fmt.Printf("decay: bad grace %q\n", args[1])
+			return
+		}
+		ctx.DecayGrace = v
+	}
+	if len(args) > 0 {
+		journalf(ctx, "DECAY half-life=%d grace=%d", ctx.TransHalfLife, ctx.DecayGrace)
+	}
+	if ctx.TransHalfLife <= 0 {
+		fmt.Println("Decay = OFF")
+		return
+	}
+	fmt.Printf("Decay: half-life=%d ticks after %d idle ticks\n", ctx.TransHalfLife, ctx.DecayGrace)
+	for _, d := range decayingPredictions(ctx, 8) {
+		fmt.Printf("           decaying %s\n", d)
+	}
+}
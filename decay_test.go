@@ -0,0 +1,56 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDecayUnobserved(t *testing.T) {
+	tests := []struct {
+		name     string
+		idle     int
+		observed bool
+		pinned   bool
+		want     float64 // remaining weight; 0 = forgotten
+	}{
+		{"within grace", 5, false, false, 1},
+		{"one half-life", 15, false, false, 0.5},
+		{"two half-lives", 25, false, false, 0.25},
+		{"observed", 25, true, false, 1},
+		{"pinned", 25, false, true, 1},
+		{"forgotten", 60, false, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			ctx.TransHalfLife, ctx.DecayGrace = 10, 5
+			ctx.TransCounts["(1>2)"] = map[string]float64{"3": 1}
+			ctx.BestPred["(1>2)"], ctx.PredConf["(1>2)"] = "3", 0.8
+			ctx.LastObserved["(1>2)"] = ctx.Tick
+			if tt.pinned {
+				PinStruct(ctx, "(1>2)")
+			}
+			for i := 0; i < tt.idle; i++ {
+				ctx.Tick++
+				if tt.observed {
+					ctx.LastObserved["(1>2)"] = ctx.Tick
+				}
+				decayUnobserved(ctx)
+			}
+
+			w := ctx.TransCounts["(1>2)"]["3"]
+			if math.Abs(w-tt.want) > 1e-9 {
+				t.Fatalf("weight = %.4f, want %.4f", w, tt.want)
+			}
+			if tt.want == 0 {
+				if _, ok := ctx.BestPred["(1>2)"]; ok {
+					t.Errorf("prediction kept after its weights decayed away")
+				}
+				return
+			}
+			if conf := ctx.PredConf["(1>2)"]; math.Abs(conf-0.8*tt.want) > 1e-9 {
+				t.Errorf("PredConf = %.4f, want %.4f", conf, 0.8*tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Digest hashes the full learned and runtime state of the field.
+// Two runs over the same input must produce the same digest after every
+// tick; any difference points at order-dependent (non-deterministic) code.
+// Maps are written in sorted key order, blocks by ID with all their fields.
+func Digest(ctx *Context) string {
+	h := fnv.New64a()
+	writeState(h, ctx)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+func writeState(w io.Writer, ctx *Context) {
+	fmt.Fprintf(w, "tick=%d energy=%v errttl=%d last=%q prev=%q\n", ctx.Tick, ctx.Energy, ctx.ErrTTL, ctx.LastSens, ctx.PrevSens)
+	fmt.Fprintf(w, "order=%v\n", ctx.Order)
+	for _, id := range sortedKeys(ctx.Blocks) {
+		fmt.Fprintf(w, "block %s=%+v\n", id, ctx.Blocks[id])
+	}
+
+	// fmt prints maps with sorted keys, so plain %v is order-stable.
+	fmt.Fprintf(w, "sensors=%v\n", ctx.Sensors)
+	fmt.Fprintf(w, "seen pairs=%v seq=%v compose=%v gaps=%v gapbase=%v\n", ctx.SeenPairs, ctx.SeenSeq, ctx.SeenComposes, ctx.SeenGaps, ctx.GapBase)
+	fmt.Fprintf(w, "trans=%v\n", ctx.TransCounts)
+	fmt.Fprintf(w, "best=%v conf=%v\n", ctx.BestPred, ctx.PredConf)
+	fmt.Fprintf(w, "pending=%v this=%v\n", ctx.PendingExpect, ctx.ThisExpect)
+	fmt.Fprintf(w, "prevstruct=%v thisstruct=%v mass=%v\n", ctx.PrevStructSet, ctx.ThisStructSet, ctx.ThisStructMass)
+	fmt.Fprintf(w, "inhib=%v cooldown=%v\n", ctx.Inhib, ctx.ErrCooldown)
+	fmt.Fprintf(w, "lastfire=%v elig=%v envbind=%v\n", ctx.BlockLastFire, ctx.Elig, ctx.EnvBind)
+	fmt.Fprintf(w, "chan last=%v prev=%v tick=%v errs=%v\n", ctx.LastByChan, ctx.PrevByChan, ctx.ChanTick, ctx.ChanErrs)
+	fmt.Fprintf(w, "num last=%v\n", ctx.NumLast)
+	fmt.Fprintf(w, "temporal seen=%v interval=%v run=%v log=%v\n", ctx.LastSeenAt, ctx.LastInterval, ctx.IntervalRun, ctx.SensLog)
+	fmt.Fprintf(w, "timed=%v\n", ctx.TimedExpect)
+	fmt.Fprintf(w, "rand=%v seed=%d draws=%d\n", ctx.Rand != nil, ctx.Seed, ctx.RandDraws)
+	fmt.Fprintf(w, "pinned=%v observed=%v\n", ctx.Pinned, ctx.LastObserved)
+	for _, k := range sortedKeys(ctx.Exceptions) {
+		fmt.Fprintf(w, "exc %s=%+v\n", k, *ctx.Exceptions[k])
+	}
+	fmt.Fprintf(w, "excev=%v expctx=%v basehit=%v\n", ctx.ExceptEvidence, ctx.ExpectCtx, ctx.BaseHitCtx)
+	fmt.Fprintf(w, "negev=%v issued=%v after=%s\n", ctx.NegEvidence, ctx.PredIssued, ctx.PredIssuedAfter)
+	fmt.Fprintf(w, "kernel=%s explained=%v\n", ctx.InhibKernel, ctx.ExplainedAway)
+	fmt.Fprintf(w, "costs=%v shed=%v cutoff=%d\n", ctx.BlockCosts, ctx.ShedBelow, ctx.ShedCutoff)
+	for _, t := range sortedKeys(ctx.EnergyUse) {
+		fmt.Fprintf(w, "use %s=%+v\n", t, *ctx.EnergyUse[t])
+	}
+
+	sched := append(SignalHeap(nil), ctx.Scheduled...)
+	sort.Sort(sched)
+	fmt.Fprintf(w, "scheduled=%v\n", sched)
+}
+
+// recordDigest stores the digest of the tick that just ended when tracking is on.
+func recordDigest(ctx *Context) {
+	if ctx.TrackDigest {
+		ctx.Digests = append(ctx.Digests, Digest(ctx))
+	}
+}
+
+// replayForDigests runs a REPL script on a fresh context with all output
+// discarded and returns the digest after every tick.
+func replayForDigests(lines []string) []string {
+	stdout := os.Stdout
+	if devnull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+		os.Stdout = devnull
+		defer func() {
+			os.Stdout = stdout
+			devnull.Close()
+		}()
+	}
+
+	ctx := NewContext()
+	ctx.TrackDigest = true
+	sess := newSession(ctx, false)
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if sess.handle(line) {
+			break
+		}
+	}
+	return ctx.Digests
+}
+
+// checkDeterminism runs the script in path (stdin when empty) twice and
+// compares the per-tick digests. It returns the process exit code.
+func checkDeterminism(path string) int {
+	var r io.Reader = os.Stdin
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Println("check-determinism:", err)
+			return 2
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var lines []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		fmt.Println("check-determinism:", err)
+		return 2
+	}
+
+	a := replayForDigests(lines)
+	b := replayForDigests(lines)
+
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			fmt.Printf("DETERMINISM: FAIL at tick %d: %s != %s\n", i+1, a[i], b[i])
+			return 1
+		}
+	}
+	if len(a) != len(b) {
+		fmt.Printf("DETERMINISM: FAIL: runs took %d and %d ticks\n", len(a), len(b))
+		return 1
+	}
+	final := "-"
+	if n > 0 {
+		final = a[n-1]
+	}
+	fmt.Printf("DETERMINISM: OK (%d ticks, final digest %s)\n", n, final)
+	return 0
+}
+
+// cmdDigest handles: digest [on|off]
+func cmdDigest(ctx *Context, args []string) {
+	if len(args) > 0 {
+		ctx.TrackDigest = args[0] == "on"
+		if !ctx.TrackDigest {
+			ctx.Digests = nil
+		}
+	}
+	fmt.Printf("Digest t=%03d %s (per-tick tracking=%v, %d recorded)\n", ctx.Tick, Digest(ctx), ctx.TrackDigest, len(ctx.Digests))
+}
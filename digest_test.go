@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+var digestScript = []string{"train", "1 2 3", "1 2 3", "# comment", "1 2 4", "test", "1 2"}
+
+func TestReplayDigestsReproducible(t *testing.T) {
+	a := replayForDigests(digestScript)
+	b := replayForDigests(digestScript)
+	if len(a) != 11 {
+		t.Fatalf("%d digests, want one per tick (11)", len(a))
+	}
+	if !slices.Equal(a, b) {
+		t.Errorf("same script gave different digests:\n%v\n%v", a, b)
+	}
+	for i := 1; i < len(a); i++ {
+		if a[i] == a[i-1] {
+			t.Errorf("digest unchanged between ticks %d and %d", i, i+1)
+		}
+	}
+
+	c := replayForDigests([]string{"train", "1 2 3", "1 2 3", "1 2 5"})
+	if c[5] != a[5] || c[8] == a[8] {
+		t.Errorf("digests must match up to the first different token and differ after it")
+	}
+}
+
+func TestCheckDeterminism(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.txt")
+	data := ""
+	for _, l := range digestScript {
+		data += l + "\n"
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if code := checkDeterminism(path); code != 0 {
+		t.Errorf("checkDeterminism = %d, want 0", code)
+	}
+	if code := checkDeterminism(filepath.Join(t.TempDir(), "missing")); code != 2 {
+		t.Errorf("checkDeterminism on a missing file = %d, want 2", code)
+	}
+}
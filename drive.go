@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Drive values with a plasticity effect. Any other K_DRIVE value
+// propagates through the field as an ordinary signal.
+const (
+	DriveReward = "REWARD"
+	DrivePunish = "PUNISH"
+)
+
+// applyDrive applies REWARD/PUNISH signals from incoming to everything that
+// is still eligible, then decays the traces.
+//
+// Reward strengthens ActionBlock links and TransCounts entries in proportion
+// to their trace. Punishment weakens them and adds inhibition, so the same
+// action or expectation is suppressed for the next few ticks.
+func applyDrive(ctx *Context, incoming []Signal) {
+	r := 0.0
+	for _, s := range incoming {
+		if s.Kind != K_DRIVE {
+			continue
+		}
+		switch s.Value {
+		case DriveReward:
+			r += s.Mass
+		case DrivePunish:
+			r -= s.Mass
+		}
+	}
+
+	if r != 0 && ctx.LearningEnabled && len(ctx.Elig) > 0 {
+		keys := make([]string, 0, len(ctx.Elig))
+		for k := range ctx.Elig {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		touched := 0
+		for _, k := range keys {
+			e := ctx.Elig[k]
+			d := ctx.RewardGain * r * e
+
+			if strings.HasPrefix(k, "TRANS:") {
+				arrow := strings.LastIndex(k, "->")
+				if arrow < 0 {
+					continue
+				}
+				st, tok := k[len("TRANS:"):arrow], k[arrow+2:]
+				m, ok := ctx.TransCounts[st]
+				if !ok {
+					continue
+				}
+				w := m[tok] + d
+				if w > 3.00 {
+					w = 3.00
+				}
+				if w < 0.05 {
+					delete(m, tok)
+				} else {
+					m[tok] = w
+				}
+				if r < 0 {
+					ctx.Inhib[punishKey(st, tok)] += ctx.PunishInhib * -r * e
+				}
+				touched++
+				continue
+			}
+			if strings.HasPrefix(k, "BIND:") {
+				bk := k[len("BIND:"):]
+				v := bindValue(ctx, bk) + d
+				if v > 3.0 {
+					v = 3.0
+				}
+				if v < 0.1 {
+					v = 0.1
+				}
+				ctx.EnvBind[bk] = v
+				touched++
+				continue
+			}
+
+			ab, ok := ctx.Blocks[k].(*ActionBlock)
+			if !ok {
+				continue
+			}
+			ab.gain += d
+			if ab.gain > 3.0 {
+				ab.gain = 3.0
+			}
+			if ab.gain < 0.1 {
+				ab.gain = 0.1
+			}
+			if r < 0 {
+				ctx.Inhib[ab.actionName] += ctx.PunishInhib * -r * e
+			}
+			touched++
+		}
+
+		kind := "REWARD"
+		if r < 0 {
+			kind = "PUNISH"
+		}
+		ctx.TrainEvents = append(ctx.TrainEvents,
+			fmt.Sprintf("+++ %s %.2f applied to %d eligible traces", kind, r, touched))
+	}
+
+	for k, e := range ctx.Elig {
+		e *= ctx.EligDecay
+		if e < 0.01 {
+			delete(ctx.Elig, k)
+		} else {
+			ctx.Elig[k] = e
+		}
+	}
+}
+
+// driveSignal builds the K_DRIVE signal that reports reward r to the field.
+// ok is false for r == 0.
+func driveSignal(r float64, from string, tick int) (Signal, bool) {
+	switch {
+	case r > 0:
+		return Signal{Kind: K_DRIVE, Value: DriveReward, Mass: r, Time: tick, From: from}, true
+	case r < 0:
+		return Signal{Kind: K_DRIVE, Value: DrivePunish, Mass: -r, Time: tick, From: from}, true
+	}
+	return Signal{}, false
+}
+
+// actionGains lists ActionBlock link strengths that reward has moved away from 1.
+func actionGains(ctx *Context, n int) []string {
+	out := make([]string, 0, n)
+	for _, id := range ctx.Order {
+		ab, ok := ctx.Blocks[id].(*ActionBlock)
+		if !ok || ab.gain == 1.0 {
+			continue
+		}
+		out = append(out, fmt.Sprintf("%s:%.2f", ab.actionName, ab.gain))
+	}
+	sort.Strings(out)
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// cmdDrive handles: drive <reward|punish|name> [mass]
+// It runs one tick with no sensory input carrying the drive signal.
+func cmdDrive(ctx *Context, args []string) {
+	if len(args) == 0 {
+		fmt.Println("usage: drive <reward|punish|name> [mass]")
+		return
+	}
+	mass := 1.0
+	if len(args) > 1 {
+		v, err := strconv.ParseFloat(args[1], 64)
+		if err != nil || v <= 0 {
+			fmt.Printf("drive: bad mass %q\n", args[1])
+			return
+		}
+		mass = v
+	}
+	val := strings.ToUpper(args[0])
+	ctx.TrainEvents = ctx.TrainEvents[:0]
+	RunTick(ctx, []Signal{{Kind: K_DRIVE, Value: val, Mass: mass, Time: ctx.Tick, From: "USER"}})
+
+	cprintf(C_GRAY, "t=%03d DRIVE=%s mass=%.2f\n", ctx.Tick, val, mass)
+	for _, te := range ctx.TrainEvents {
+		cprintf(C_GREEN, "           %s\n", te)
+	}
+}
+
+// punishKey is the inhibition key under which punishment suppresses the
+// prediction st->tok; it decays with the rest of ctx.Inhib.
+func punishKey(st, tok string) string { return "PUNISH:" + st + "->" + tok }
+
+// predInhibited reports whether the prediction st->tok is suppressed, either
+// because the structure itself is strongly inhibited or because the
+// transition was punished.
+func predInhibited(ctx *Context, st, tok string) bool {
+	return ctx.Inhib[st] > 0.7 || ctx.Inhib[punishKey(st, tok)] > 0.7
+}
@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestDriveOnExpectation(t *testing.T) {
+	tests := []struct {
+		name     string
+		drive    string
+		wantArm  bool
+		wantMore bool // the transition weight grows
+	}{
+		{"reward", DriveReward, true, true},
+		{"punish", DrivePunish, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			trainCycle(ctx, []string{"1", "2", "3"}, 10)
+			before := ctx.TransCounts["[1-2]"]["3"]
+
+			clearFloatMap(ctx.Elig)
+			ctx.Elig["TRANS:[1-2]->3"] = 1
+			RunTick(ctx, []Signal{{Kind: K_DRIVE, Value: tt.drive, Mass: 2, Time: ctx.Tick, From: "USER"}})
+			if got := ctx.TransCounts["[1-2]"]["3"] > before; got != tt.wantMore {
+				t.Errorf("weight %.2f -> %.2f", before, ctx.TransCounts["[1-2]"]["3"])
+			}
+
+			feedToken(ctx, "1")
+			out := feedToken(ctx, "2")
+			if got := ctx.PendingExpect["[1-2]"] == "3"; got != tt.wantArm {
+				t.Errorf("armed [1-2]->3 = %v, want %v", got, tt.wantArm)
+			}
+			for _, s := range out {
+				if s.Kind == K_PRED && s.Value == "[1-2]->3" && !tt.wantArm {
+					t.Errorf("punished prediction still emitted by %s", s.From)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Operator edits correct or seed the model by hand. Every edit is journaled.
+
+// learnedIDOf resolves a structure name ("[a-b]", "(a>b)", ...) or a full
+// block ID to the ID of an existing block.
+func learnedIDOf(ctx *Context, name string) (string, bool) {
+	if _, ok := ctx.Blocks[name]; ok {
+		return name, true
+	}
+	for _, p := range learnedPrefixes {
+		if _, ok := ctx.Blocks[p+name]; ok {
+			return p + name, true
+		}
+	}
+	return "", false
+}
+
+// PinStruct protects a structure from forgetting, budget eviction and sleep merges.
+func PinStruct(ctx *Context, st string) {
+	if ctx.Pinned == nil {
+		ctx.Pinned = make(map[string]bool)
+	}
+	ctx.Pinned[st] = true
+	journalf(ctx, "EDIT pin %s", st)
+}
+
+func UnpinStruct(ctx *Context, st string) {
+	delete(ctx.Pinned, st)
+	journalf(ctx, "EDIT unpin %s", st)
+}
+
+// DeleteKnowledge removes a block; learned structures take their dependents
+// (compose blocks built on them and action links) with them. Deleting a
+// sensor forgets its token, deleting a receptive field removes it from its field.
+func DeleteKnowledge(ctx *Context, name string) ([]string, error) {
+	id, ok := learnedIDOf(ctx, name)
+	if !ok {
+		return nil, fmt.Errorf("no block %q", name)
+	}
+	var removed []string
+	st := structOfID(id)
+	switch b := ctx.Blocks[id].(type) {
+	case *RangeSensorBlock:
+		removeNumField(ctx, b)
+		removed = []string{id}
+	case *SensorBlock:
+		delete(ctx.Sensors, b.token)
+		removeBlock(ctx, id)
+		removed = []string{id}
+	default:
+		if st != "" {
+			removed = evictStructure(ctx, id)
+			delete(ctx.Pinned, st)
+		} else {
+			removeBlock(ctx, id)
+			removed = []string{id}
+		}
+	}
+	journalf(ctx, "EDIT delete %s (%d blocks)", id, len(removed))
+	return removed, nil
+}
+
+// InjectStructure crystallizes a structure directly: a pair "[a-b]",
+// a sequence "(a>b)" or a composition "[[a-b]-c]". Pairs and sequences are
+// injected mature, so they fire on the next match. The structure's evidence
+// is marked crystallized, as if it had been learned.
+func InjectStructure(ctx *Context, name string) error {
+	if _, ok := learnedIDOf(ctx, name); ok {
+		return fmt.Errorf("%s already exists", name)
+	}
+
+	var id string
+	switch {
+	case strings.HasPrefix(name, "[[") && strings.Contains(name, "]-"):
+		inner := name[1 : len(name)-1]
+		i := strings.LastIndex(inner, "]-")
+		base, x := inner[:i+1], inner[i+2:]
+		if _, ok := ctx.Blocks["COACT:"+base]; !ok || x == "" {
+			return fmt.Errorf("compose needs an existing base pair %s", base)
+		}
+		ensureSensor(ctx, x)
+		cb := NewComposeBlock(base, x)
+		ctx.AddBlock(cb)
+		ctx.AddBlock(NewActionBlock(cb.name, "ACT_ON_"+cb.name))
+		ctx.SeenComposes[base+"||"+x] = -1.0
+		id = cb.ID()
+
+	case strings.HasPrefix(name, "["):
+		a, b, ok := parsePairMembers(name)
+		if !ok {
+			return fmt.Errorf("bad pair %q (want [a-b])", name)
+		}
+		cb := NewCoActBlock(a, b)
+		if _, ok := ctx.Blocks[cb.ID()]; ok {
+			return fmt.Errorf("%s already exists", cb.name)
+		}
+		ensureSensor(ctx, a)
+		ensureSensor(ctx, b)
+		cb.mature = true
+		ctx.AddBlock(cb)
+		ctx.SeenPairs[pairKey(a, b)] = -1.0
+		id = cb.ID()
+
+	case strings.HasPrefix(name, "(") && strings.HasSuffix(name, ")"):
+		ab := strings.SplitN(name[1:len(name)-1], ">", 2)
+		if len(ab) != 2 || ab[0] == "" || ab[1] == "" {
+			return fmt.Errorf("bad sequence %q (want (a>b))", name)
+		}
+		ensureSensor(ctx, ab[0])
+		ensureSensor(ctx, ab[1])
+		sb := NewSeqBlock(ab[0], ab[1])
+		sb.mature = true
+		ctx.AddBlock(sb)
+		ctx.AddBlock(NewActionBlock(sb.name, "ACT_ON_"+sb.name))
+		ctx.SeenSeq[ab[0]+">"+ab[1]] = -1.0
+		id = sb.ID()
+
+	default:
+		return fmt.Errorf("unknown structure form %q", name)
+	}
+
+	if _, ok := ctx.Blocks[id]; !ok {
+		return fmt.Errorf("inject %s: block %s was not created", name, id)
+	}
+	journalf(ctx, "EDIT inject %s", name)
+	return nil
+}
+
+// SetTransWeight sets TransCounts[st][tok] (w <= 0 removes it) and
+// re-derives the structure's prediction.
+func SetTransWeight(ctx *Context, st, tok string, w float64) {
+	if w <= 0 {
+		delete(ctx.TransCounts[st], tok)
+	} else {
+		if ctx.TransCounts[st] == nil {
+			ctx.TransCounts[st] = make(map[string]float64)
+		}
+		ctx.TransCounts[st][tok] = w
+	}
+	refreshPrediction(ctx, st)
+	journalf(ctx, "EDIT weight %s->%s=%.2f (best=%s conf=%.2f)", st, tok, w, ctx.BestPred[st], ctx.PredConf[st])
+}
+
+// SetInhib forces an inhibition level; it then decays like any other.
+func SetInhib(ctx *Context, key string, v float64) {
+	ctx.Inhib[key] = v
+	journalf(ctx, "EDIT inhib %s=%.2f", key, v)
+}
+
+func ClearInhib(ctx *Context, key string) {
+	delete(ctx.Inhib, key)
+	journalf(ctx, "EDIT inhib %s cleared", key)
+}
+
+// RenameAction renames every action link emitting oldName. Link state
+// (gain, accumulation), eligibility, inhibition and group are carried over.
+func RenameAction(ctx *Context, oldName, newName string) (int, error) {
+	n := 0
+	for _, id := range sortedKeys(ctx.Blocks) {
+		ab, ok := ctx.Blocks[id].(*ActionBlock)
+		if !ok || ab.actionName != oldName {
+			continue
+		}
+		if _, taken := ctx.Blocks["ACTIONBLOCK:"+newName+"<-"+ab.targetStruct]; taken {
+			return n, fmt.Errorf("action %q already linked to %s", newName, ab.targetStruct)
+		}
+		removeBlock(ctx, id)
+		ab.actionName = newName
+		ctx.AddBlock(ab)
+		if e, ok := ctx.Elig[id]; ok {
+			delete(ctx.Elig, id)
+			ctx.Elig[ab.ID()] = e
+		}
+		n++
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("no action named %q", oldName)
+	}
+	if v, ok := ctx.Inhib[oldName]; ok {
+		delete(ctx.Inhib, oldName)
+		ctx.Inhib[newName] = v
+	}
+	if g, ok := ctx.ActionGroups[oldName]; ok {
+		delete(ctx.ActionGroups, oldName)
+		ctx.ActionGroups[newName] = g
+	}
+	if c, ok := ctx.ActionCosts[oldName]; ok {
+		delete(ctx.ActionCosts, oldName)
+		ctx.ActionCosts[newName] = c
+	}
+	journalf(ctx, "EDIT rename action %s -> %s (%d links)", oldName, newName, n)
+	return n, nil
+}
+
+// cmdEdit handles:
+//
+//	edit pin|unpin <struct>
+//	edit delete <struct|block id>
+//	edit inject <[a-b]|(a>b)|[[a-b]-c]>
+//	edit weight <struct> <tok> <w>
+//	edit inhib <key> <v|clear>
+//	edit rename <action> <new name>
+func cmdEdit(ctx *Context, args []string) {
+	usage := func() {
+		fmt.Println("usage: edit pin|unpin <struct> | edit delete <struct|id> | edit inject <[a-b]|(a>b)|[[a-b]-c]>")
+		fmt.Println("       edit weight <struct> <tok> <w> | edit inhib <key> <v|clear> | edit rename <action> <new>")
+	}
+	if len(args) < 2 {
+		usage()
+		return
+	}
+
+	switch args[0] {
+	case "pin":
+		PinStruct(ctx, args[1])
+	case "unpin":
+		UnpinStruct(ctx, args[1])
+	case "delete":
+		removed, err := DeleteKnowledge(ctx, args[1])
+		if err != nil {
+			fmt.Println("edit:", err)
+			return
+		}
+		fmt.Printf("Deleted %v\n", removed)
+		return
+	case "inject":
+		if err := InjectStructure(ctx, args[1]); err != nil {
+			fmt.Println("edit:", err)
+			return
+		}
+	case "weight":
+		if len(args) != 4 {
+			usage()
+			return
+		}
+		w, err := strconv.ParseFloat(args[3], 64)
+		if err != nil {
+			fmt.Printf("edit: bad weight %q\n", args[3])
+			return
+		}
+		SetTransWeight(ctx, args[1], args[2], w)
+	case "inhib":
+		if len(args) != 3 {
+			usage()
+			return
+		}
+		if args[2] == "clear" {
+			ClearInhib(ctx, args[1])
+			break
+		}
+		v, err := strconv.ParseFloat(args[2], 64)
+		if err != nil || v < 0 {
+			fmt.Printf("edit: bad inhibition %q\n", args[2])
+			return
+		}
+		SetInhib(ctx, args[1], v)
+	case "rename":
+		if len(args) != 3 {
+			usage()
+			return
+		}
+		if _, err := RenameAction(ctx, args[1], args[2]); err != nil {
+			fmt.Println("edit:", err)
+			return
+		}
+	default:
+		usage()
+		return
+	}
+	fmt.Printf("JOURNAL %s\n", ctx.Journal[len(ctx.Journal)-1])
+}
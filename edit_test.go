@@ -0,0 +1,144 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInjectDeleteRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup []string // structures injected first
+		id    string
+	}{
+		{"[1-2]", nil, "COACT:[1-2]"},
+		{"(1>2)", nil, "SEQ:(1>2)"},
+		{"[[1-2]-3]", []string{"[1-2]"}, "COMPOSE:[[1-2]-3]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			for _, s := range tt.setup {
+				if err := InjectStructure(ctx, s); err != nil {
+					t.Fatalf("setup inject %s: %v", s, err)
+				}
+			}
+			for round := 0; round < 2; round++ {
+				if err := InjectStructure(ctx, tt.name); err != nil {
+					t.Fatalf("round %d: inject: %v", round, err)
+				}
+				if _, ok := ctx.Blocks[tt.id]; !ok {
+					t.Fatalf("round %d: %s not created", round, tt.id)
+				}
+				if err := InjectStructure(ctx, tt.name); err == nil {
+					t.Fatalf("round %d: second inject succeeded", round)
+				}
+				if _, err := DeleteKnowledge(ctx, tt.name); err != nil {
+					t.Fatalf("round %d: delete: %v", round, err)
+				}
+				if _, ok := ctx.Blocks[tt.id]; ok {
+					t.Fatalf("round %d: %s still present after delete", round, tt.id)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteKnowledgeRemovesDependents(t *testing.T) {
+	ctx := NewContext()
+	for _, s := range []string{"[1-2]", "[[1-2]-3]"} {
+		if err := InjectStructure(ctx, s); err != nil {
+			t.Fatalf("inject %s: %v", s, err)
+		}
+	}
+	removed, err := DeleteKnowledge(ctx, "[1-2]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"COACT:[1-2]", "COMPOSE:[[1-2]-3]", "ACTIONBLOCK:ACT_ON_[[1-2]-3]<-[[1-2]-3]"} {
+		if _, ok := ctx.Blocks[id]; ok {
+			t.Errorf("%s still present", id)
+		}
+		if !containsStr(removed, id) {
+			t.Errorf("%s not reported in %v", id, removed)
+		}
+	}
+	if _, ok := ctx.SeenComposes["[1-2]||3"]; ok {
+		t.Errorf("compose evidence not reset")
+	}
+}
+
+func TestDeleteKnowledgeSensors(t *testing.T) {
+	ctx := NewContext()
+	ensureSensor(ctx, "x")
+	ensureSensor(ctx, "temp=23")
+	f := numFieldByToken(ctx, "temp@20..30")
+	if f == nil {
+		t.Fatal("no receptive field for temp=23")
+	}
+
+	if _, err := DeleteKnowledge(ctx, "SENSOR:x"); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Sensors["x"] {
+		t.Errorf("Sensors[x] still set")
+	}
+	if !ensureSensor(ctx, "x") {
+		t.Errorf("sensor x not recreated after delete")
+	}
+
+	if _, err := DeleteKnowledge(ctx, f.ID()); err != nil {
+		t.Fatal(err)
+	}
+	if numFieldByToken(ctx, "temp@20..30") != nil {
+		t.Errorf("field still listed in NumFields")
+	}
+	if _, ok := ctx.Blocks[f.ID()]; ok {
+		t.Errorf("%s still present", f.ID())
+	}
+}
+
+func TestSleepMergeDropsRenamedActionLink(t *testing.T) {
+	ctx := NewContext()
+	for _, s := range []string{"[1-2]", "(1>2)"} {
+		if err := InjectStructure(ctx, s); err != nil {
+			t.Fatalf("inject %s: %v", s, err)
+		}
+		SetTransWeight(ctx, s, "3", 2.0)
+	}
+	if _, err := RenameAction(ctx, "ACT_ON_(1>2)", "go"); err != nil {
+		t.Fatal(err)
+	}
+	Sleep(ctx, 0)
+	if _, ok := ctx.Blocks["SEQ:(1>2)"]; ok {
+		t.Fatal("(1>2) was not merged into [1-2]")
+	}
+	for _, id := range sortedKeys(ctx.Blocks) {
+		if ab, ok := ctx.Blocks[id].(*ActionBlock); ok && ab.targetStruct == "(1>2)" {
+			t.Errorf("action link %s left behind by the merge", id)
+		}
+	}
+}
+
+func TestGatedConf(t *testing.T) {
+	tests := []struct {
+		name   string
+		m      map[string]float64
+		target string
+		want   float64
+	}{
+		{"empty", nil, "3", 0},
+		{"no target", map[string]float64{"3": 1}, "", 0},
+		{"unknown target", map[string]float64{"3": 1}, "4", 0},
+		{"certain", map[string]float64{"3": 1}, "3", 0.99},
+		{"little evidence", map[string]float64{"3": 0.44}, "3", 0.5},
+		{"tie", map[string]float64{"3": 1, "4": 1}, "3", 0.5 / 1.35},
+		{"clear margin", map[string]float64{"3": 2, "4": 1}, "3", 2.0 / 3},
+		{"negligible", map[string]float64{"3": 0.005}, "3", 0},
+	}
+	for _, tt := range tests {
+		if got := gatedConf(tt.m, tt.target); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: gatedConf = %.4f, want %.4f", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Environment closes the loop between the field and the outside world.
+// Actions emitted in one tick are applied by Step, and the returned
+// observations become the K_SENS input of the next tick.
+type Environment interface {
+	Name() string
+
+	// Reset starts a new run and returns the first observations.
+	Reset() []string
+
+	// Step applies the actions of the last tick and returns the next observations
+	// together with the scalar reward earned by that step. The runner feeds the
+	// reward back as a K_DRIVE signal on the next tick.
+	Step(actions []Signal) (observations []string, reward float64)
+}
+
+// EnvStep is a trace record of one loop iteration.
+type EnvStep struct {
+	Tick    int
+	Obs     []string
+	Actions []string // emitted actions; learned ones as "action=>move"
+	Lost    []string // actions that lost arbitration, with reasons
+	Move    string   // environment action that was applied
+	Reward  float64
+}
+
+// EnvReport summarizes a closed-loop run.
+type EnvReport struct {
+	Env         string
+	Steps       []EnvStep
+	TotalReward float64
+	MoveCounts  map[string]int
+}
+
+// RunEnvLoop connects env to RunTick for the given number of steps.
+func RunEnvLoop(ctx *Context, env Environment, steps int) EnvReport {
+	rep := EnvReport{Env: env.Name(), MoveCounts: make(map[string]int)}
+
+	resetEpisodeBoundary(ctx)
+	obs := env.Reset()
+	reward := 0.0
+
+	for i := 0; i < steps; i++ {
+		in := make([]Signal, 0, len(obs)+1)
+		for _, o := range obs {
+			ensureSensor(ctx, o)
+			in = append(in, Signal{Kind: K_SENS, Value: o, Mass: 1.0, Time: ctx.Tick, From: "ENV:" + env.Name()})
+		}
+		// The reward earned by the previous step arrives with the next observation.
+		if ds, ok := driveSignal(reward, "ENV:"+env.Name(), ctx.Tick); ok {
+			in = append(in, ds)
+		}
+
+		out := RunTick(ctx, in)
+
+		actions := make([]Signal, 0, 2)
+		for _, s := range out {
+			if s.Kind == K_ACTION {
+				actions = append(actions, s)
+			}
+		}
+		actions, names := bindActions(ctx, env, strings.Join(obs, "+"), actions)
+
+		next, r := env.Step(actions)
+		move := lastMove(env)
+		lost := append([]string(nil), ctx.ActionLosers...)
+		rep.Steps = append(rep.Steps, EnvStep{Tick: ctx.Tick, Obs: obs, Actions: names, Lost: lost, Move: move, Reward: r})
+		rep.TotalReward += r
+		rep.MoveCounts[move]++
+		obs, reward = next, r
+	}
+	return rep
+}
+
+// envMover is implemented by the reference environments to expose the move applied by the last Step.
+type envMover interface {
+	LastMove() string
+}
+
+// envMoveSet is implemented by the reference environments to expose their moves.
+type envMoveSet interface {
+	Moves() []string
+}
+
+const (
+	bindInit  = 1.0 // value of a binding that was never played
+	bindDecay = 0.9 // value kept each time a binding is played; reward adds to it
+)
+
+// bindValue returns the value of the binding "state|action->move".
+func bindValue(ctx *Context, key string) float64 {
+	if v, ok := ctx.EnvBind[key]; ok {
+		return v
+	}
+	return bindInit
+}
+
+// bindActions translates learned actions into environment moves. An action
+// named like a move is passed through. Any other action is bound per state:
+// every move keeps a value for (state, action), the best one is played, and
+// the binding becomes eligible for the reward that follows. Playing a binding
+// costs a little value, so unrewarded moves give way to the untried ones.
+func bindActions(ctx *Context, env Environment, state string, actions []Signal) ([]Signal, []string) {
+	names := make([]string, 0, len(actions))
+	ms, ok := env.(envMoveSet)
+	if !ok {
+		for _, a := range actions {
+			names = append(names, a.Value)
+		}
+		return actions, names
+	}
+	moves := ms.Moves()
+
+	out := make([]Signal, 0, len(actions))
+	for _, a := range actions {
+		if containsStr(moves, a.Value) || len(moves) == 0 {
+			out = append(out, a)
+			names = append(names, a.Value)
+			continue
+		}
+		prefix := state + "|" + a.Value + "->"
+		best, bestV := "", 0.0
+		for _, m := range moves {
+			if v := bindValue(ctx, prefix+m); best == "" || v > bestV {
+				best, bestV = m, v
+			}
+		}
+		key := prefix + best
+		if ctx.LearningEnabled {
+			ctx.EnvBind[key] = bestV * bindDecay
+			ctx.Elig["BIND:"+key] = 1.0
+		}
+		names = append(names, a.Value+"=>"+best)
+		a.Value = best
+		out = append(out, a)
+	}
+	return out, names
+}
+
+func lastMove(env Environment) string {
+	if m, ok := env.(envMover); ok {
+		return m.LastMove()
+	}
+	return ""
+}
+
+// pickEnvAction maps emitted action signals onto an environment's action set.
+// The strongest signal naming one of moves wins; learned actions have been
+// bound to moves by bindActions. ok is false when no move was emitted.
+func pickEnvAction(actions []Signal, moves []string) (idx int, ok bool) {
+	idx = -1
+	var best Signal
+	for _, a := range actions {
+		for i, m := range moves {
+			if a.Value != m {
+				continue
+			}
+			if idx < 0 || a.Mass > best.Mass || (a.Mass == best.Mass && a.Value < best.Value) {
+				idx, best = i, a
+			}
+		}
+	}
+	return idx, idx >= 0
+}
+
+// CorridorEnv is a 1-D corridor. The agent starts at cell 0 and is rewarded
+// for reaching the last cell, after which it is placed back at the start.
+// When no action is emitted the agent drifts randomly (motor babbling).
+type CorridorEnv struct {
+	Length int
+	pos    int
+	move   string
+	rng    *rand.Rand
+	seed   int64
+}
+
+func NewCorridorEnv(length int, seed int64) *CorridorEnv {
+	if length < 2 {
+		length = 2
+	}
+	return &CorridorEnv{Length: length, seed: seed}
+}
+
+func (e *CorridorEnv) Name() string     { return "corridor" }
+func (e *CorridorEnv) LastMove() string { return e.move }
+func (e *CorridorEnv) Moves() []string  { return []string{"left", "right"} }
+
+func (e *CorridorEnv) Reset() []string {
+	e.rng = rand.New(rand.NewSource(e.seed))
+	e.pos = 0
+	e.move = ""
+	return []string{e.obs()}
+}
+
+func (e *CorridorEnv) obs() string { return "c" + strconv.Itoa(e.pos) }
+
+func (e *CorridorEnv) Step(actions []Signal) ([]string, float64) {
+	moves := e.Moves()
+	i, ok := pickEnvAction(actions, moves)
+	if !ok {
+		i = e.rng.Intn(len(moves))
+	}
+	e.move = moves[i]
+
+	if e.move == "left" && e.pos > 0 {
+		e.pos--
+	}
+	if e.move == "right" && e.pos < e.Length-1 {
+		e.pos++
+	}
+
+	if e.pos == e.Length-1 {
+		o := e.obs()
+		e.pos = 0
+		return []string{o}, 1.0
+	}
+	return []string{e.obs()}, 0
+}
+
+// GridEnv is a W×H gridworld with the goal in the far corner.
+// Observations are cell tokens like "g2_1".
+type GridEnv struct {
+	W, H int
+	x, y int
+	move string
+	rng  *rand.Rand
+	seed int64
+}
+
+func NewGridEnv(w, h int, seed int64) *GridEnv {
+	if w < 2 {
+		w = 2
+	}
+	if h < 1 {
+		h = 1
+	}
+	return &GridEnv{W: w, H: h, seed: seed}
+}
+
+func (e *GridEnv) Name() string     { return "grid" }
+func (e *GridEnv) LastMove() string { return e.move }
+func (e *GridEnv) Moves() []string  { return []string{"up", "down", "left", "right"} }
+
+func (e *GridEnv) Reset() []string {
+	e.rng = rand.New(rand.NewSource(e.seed))
+	e.x, e.y = 0, 0
+	e.move = ""
+	return []string{e.obs()}
+}
+
+func (e *GridEnv) obs() string { return fmt.Sprintf("g%d_%d", e.x, e.y) }
+
+func (e *GridEnv) Step(actions []Signal) ([]string, float64) {
+	moves := e.Moves()
+	i, ok := pickEnvAction(actions, moves)
+	if !ok {
+		i = e.rng.Intn(len(moves))
+	}
+	e.move = moves[i]
+
+	switch e.move {
+	case "up":
+		if e.y > 0 {
+			e.y--
+		}
+	case "down":
+		if e.y < e.H-1 {
+			e.y++
+		}
+	case "left":
+		if e.x > 0 {
+			e.x--
+		}
+	case "right":
+		if e.x < e.W-1 {
+			e.x++
+		}
+	}
+
+	if e.x == e.W-1 && e.y == e.H-1 {
+		o := e.obs()
+		e.x, e.y = 0, 0
+		return []string{o}, 1.0
+	}
+	return []string{e.obs()}, 0
+}
+
+// BanditEnv is a k-armed bandit. Each arm pays 1 with its own probability.
+// The observation reports the pulled arm and the outcome, e.g. "arm1.win".
+type BanditEnv struct {
+	Probs []float64
+	move  string
+	rng   *rand.Rand
+	seed  int64
+}
+
+func NewBanditEnv(probs []float64, seed int64) *BanditEnv {
+	return &BanditEnv{Probs: probs, seed: seed}
+}
+
+func (e *BanditEnv) Name() string     { return "bandit" }
+func (e *BanditEnv) LastMove() string { return e.move }
+
+func (e *BanditEnv) Moves() []string {
+	moves := make([]string, len(e.Probs))
+	for i := range e.Probs {
+		moves[i] = "arm" + strconv.Itoa(i)
+	}
+	return moves
+}
+
+func (e *BanditEnv) Reset() []string {
+	e.rng = rand.New(rand.NewSource(e.seed))
+	e.move = ""
+	return []string{"bandit"}
+}
+
+func (e *BanditEnv) Step(actions []Signal) ([]string, float64) {
+	moves := e.Moves()
+	i, ok := pickEnvAction(actions, moves)
+	if !ok {
+		i = e.rng.Intn(len(moves))
+	}
+	e.move = moves[i]
+
+	if e.rng.Float64() < e.Probs[i] {
+		return []string{e.move + ".win"}, 1.0
+	}
+	return []string{e.move + ".lose"}, 0
+}
+
+// newEnvByName builds one of the reference environments.
+func newEnvByName(name string, seed int64) (Environment, error) {
+	switch strings.ToLower(name) {
+	case "corridor":
+		return NewCorridorEnv(5, seed), nil
+	case "grid":
+		return NewGridEnv(3, 3, seed), nil
+	case "bandit":
+		return NewBanditEnv([]float64{0.2, 0.5, 0.8}, seed), nil
+	}
+	return nil, fmt.Errorf("unknown environment %q (corridor | grid | bandit)", name)
+}
+
+// cmdEnv handles: env <corridor|grid|bandit> [steps] [seed]
+func cmdEnv(ctx *Context, args []string) {
+	if len(args) == 0 {
+		fmt.Println("usage: env <corridor|grid|bandit> [steps] [seed]")
+		return
+	}
+	steps := 40
+	seed := int64(1)
+	if len(args) > 1 {
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v <= 0 {
+			fmt.Printf("env: bad step count %q\n", args[1])
+			return
+		}
+		steps = v
+	}
+	if len(args) > 2 {
+		v, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			fmt.Printf("env: bad seed %q\n", args[2])
+			return
+		}
+		seed = v
+	}
+
+	env, err := newEnvByName(args[0], seed)
+	if err != nil {
+		fmt.Println("env:", err)
+		return
+	}
+
+	cprintf(C_MAGENTA+C_BOLD, "ENV: %s steps=%d seed=%d\n", env.Name(), steps, seed)
+	rep := RunEnvLoop(ctx, env, steps)
+
+	for _, st := range rep.Steps {
+		line := fmt.Sprintf("t=%03d OBS=%v MOVE=%s", st.Tick, st.Obs, st.Move)
+		if len(st.Actions) > 0 {
+			line += fmt.Sprintf(" ACTION=%v", st.Actions)
+		}
+		if len(st.Lost) > 0 {
+			line += fmt.Sprintf(" LOST=%v", st.Lost)
+		}
+		if st.Reward != 0 {
+			cprintf(C_GREEN+C_BOLD, "%s REWARD=%.2f\n", line, st.Reward)
+		} else {
+			cprintf(C_GRAY, "%s\n", line)
+		}
+	}
+
+	moves := make([]string, 0, len(rep.MoveCounts))
+	for m, n := range rep.MoveCounts {
+		moves = append(moves, fmt.Sprintf("%s:%d", m, n))
+	}
+	sort.Strings(moves)
+	cprintf(C_MAGENTA, "ENV SUMMARY: %s reward=%.2f moves=%v\n", rep.Env, rep.TotalReward, moves)
+}
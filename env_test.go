@@ -0,0 +1,85 @@
+package main
+
+import "testing"
+
+func TestPickEnvAction(t *testing.T) {
+	moves := []string{"left", "right"}
+	tests := []struct {
+		name    string
+		actions []Signal
+		idx     int
+		ok      bool
+	}{
+		{"none", nil, -1, false},
+		{"unbound learned action", []Signal{{Value: "ACT_ON_(1>2)", Mass: 1}}, -1, false},
+		{"direct", []Signal{{Value: "right", Mass: 1}}, 1, true},
+		{"strongest wins", []Signal{{Value: "left", Mass: 0.5}, {Value: "right", Mass: 0.9}}, 1, true},
+		{"tie by name", []Signal{{Value: "right", Mass: 1}, {Value: "left", Mass: 1}}, 0, true},
+		{"ignores unknown", []Signal{{Value: "jump", Mass: 5}, {Value: "left", Mass: 0.1}}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx, ok := pickEnvAction(tt.actions, moves)
+			if idx != tt.idx || ok != tt.ok {
+				t.Errorf("pickEnvAction = %d, %v; want %d, %v", idx, ok, tt.idx, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBindActionsLearnsFromReward(t *testing.T) {
+	ctx := NewContext()
+	env := NewBanditEnv([]float64{0, 0, 1}, 1)
+	act := Signal{Kind: K_ACTION, Value: "ACT_ON_(1>2)", Mass: 1, From: "ACTIONBLOCK:ACT_ON_(1>2)<-(1>2)"}
+
+	play := func() string {
+		out, _ := bindActions(ctx, env, "bandit", []Signal{act})
+		if len(out) != 1 {
+			t.Fatalf("bindActions returned %d signals", len(out))
+		}
+		return out[0].Value
+	}
+
+	// Untried moves are preferred over played, unrewarded ones.
+	seen := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		seen[play()] = true
+		applyDrive(ctx, nil)
+	}
+	if len(seen) != 3 {
+		t.Fatalf("exploration played %v, want all three arms", seen)
+	}
+
+	// A rewarded binding is kept.
+	for i := 0; i < 3; i++ {
+		if m := play(); m == "arm2" {
+			applyDrive(ctx, []Signal{{Kind: K_DRIVE, Value: DriveReward, Mass: 1}})
+		} else {
+			applyDrive(ctx, nil)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if m := play(); m != "arm2" {
+			t.Fatalf("play %d chose %s after reward, want arm2", i, m)
+		}
+		applyDrive(ctx, []Signal{{Kind: K_DRIVE, Value: DriveReward, Mass: 1}})
+	}
+
+	// Bindings are per state.
+	out, _ := bindActions(ctx, env, "other", []Signal{act})
+	if out[0].Value != "arm0" {
+		t.Errorf("fresh state chose %s, want arm0", out[0].Value)
+	}
+}
+
+func TestBindActionsPassesMoves(t *testing.T) {
+	ctx := NewContext()
+	env := NewCorridorEnv(5, 1)
+	out, names := bindActions(ctx, env, "c0", []Signal{{Kind: K_ACTION, Value: "right", Mass: 1}})
+	if len(out) != 1 || out[0].Value != "right" || names[0] != "right" {
+		t.Errorf("bindActions = %v %v, want the move unchanged", out, names)
+	}
+	if len(ctx.EnvBind) != 0 {
+		t.Errorf("direct moves created bindings %v", ctx.EnvBind)
+	}
+}
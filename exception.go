@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A structure has one transition table, so "[1-2] ⇒ 3, except after 0 it is 4"
+// would otherwise need a ComposeBlock. Exceptions are context-gated rules:
+// when a structure mispredicts repeatedly in the same preceding context, the
+// observed token is learned as an exception that overrides BestPred only when
+// the structure is armed in that context.
+//
+// The context of an expectation is the latest token sensed before the
+// previous tick, i.e. the token just ahead of a two-token structure.
+// An error only counts as evidence when the base prediction was confirmed in
+// a different context since the structure's previous error; errors in every
+// context mean the regime changed, which Plasticity handles by switching.
+
+// Exception overrides BestPred[Struct] with Tok when armed after Context.
+type Exception struct {
+	Struct  string
+	Context string
+	Tok     string
+	Conf    float64
+	Hits    int
+	Misses  int
+}
+
+const (
+	exceptStep    = 0.5  // evidence per contextual error; 1.0 learns the exception
+	exceptInitCnf = 0.60 // confidence of a fresh exception
+	exceptMinConf = 0.25 // below this an exception no longer overrides
+	exceptDropCnf = 0.10 // below this it is forgotten
+)
+
+func exceptKey(st, c string) string { return st + "|" + c }
+
+// expectContext returns the context token for an expectation armed this tick.
+func expectContext(ctx *Context) string {
+	for i := len(ctx.SensLog) - 1; i >= 0; i-- {
+		if ctx.SensLog[i].Tick < ctx.Tick-1 {
+			return ctx.SensLog[i].Tok
+		}
+	}
+	return ""
+}
+
+// applyException records the context of st's expectation and returns the
+// token to expect: the exception's token when one holds, otherwise pred.
+func applyException(ctx *Context, st, pred string) string {
+	c := expectContext(ctx)
+	if c == "" {
+		return pred
+	}
+	ctx.ExpectCtx[st] = c
+	if ex, ok := ctx.Exceptions[exceptKey(st, c)]; ok && ex.Conf >= exceptMinConf {
+		return ex.Tok
+	}
+	return pred
+}
+
+// exceptionHit credits the exception behind a confirmed expectation.
+func exceptionHit(ctx *Context, st, pred string) {
+	if !ctx.LearningEnabled {
+		return
+	}
+	c, armed := ctx.ExpectCtx[st]
+	ex, ok := ctx.Exceptions[exceptKey(st, c)]
+	if !ok || ex.Tok != pred {
+		if armed && pred == ctx.BestPred[st] {
+			ctx.BaseHitCtx[st] = c
+		}
+		return
+	}
+	ex.Hits++
+	ex.Conf += 0.10
+	if ex.Conf > 0.99 {
+		ex.Conf = 0.99
+	}
+}
+
+// exceptionMiss handles a misprediction of st: a wrong exception loses
+// confidence, and recurring errors in one context accumulate evidence
+// until the actual token is learned as an exception.
+func exceptionMiss(ctx *Context, st, pred, actual string) {
+	c, ok := ctx.ExpectCtx[st]
+	if !ok || c == "" || !ctx.LearningEnabled {
+		return
+	}
+	key := exceptKey(st, c)
+	if ex, ok := ctx.Exceptions[key]; ok {
+		if ex.Tok == actual {
+			return
+		}
+		ex.Misses++
+		ex.Conf *= 0.7
+		if ex.Conf < exceptDropCnf {
+			delete(ctx.Exceptions, key)
+			ctx.TrainEvents = append(ctx.TrainEvents,
+				fmt.Sprintf("--- EXCEPTION DROPPED %s after %s ⇒ %s", st, c, ex.Tok))
+		}
+		if _, kept := ctx.Exceptions[key]; kept && ex.Tok == pred {
+			return
+		}
+	}
+	hitCtx, contrast := ctx.BaseHitCtx[st]
+	delete(ctx.BaseHitCtx, st)
+	if !contrast || hitCtx == c {
+		return
+	}
+	if !ctx.LearnPred || actual == ctx.BestPred[st] {
+		return
+	}
+
+	ek := key + "|" + actual
+	ctx.ExceptEvidence[ek] += exceptStep
+	if ctx.ExceptEvidence[ek] < 1.0 {
+		return
+	}
+	delete(ctx.ExceptEvidence, ek)
+	ctx.Exceptions[key] = &Exception{Struct: st, Context: c, Tok: actual, Conf: exceptInitCnf}
+	ctx.TrainEvents = append(ctx.TrainEvents,
+		fmt.Sprintf("+++ EXCEPTION LEARNED %s after %s ⇒ %s (base ⇒ %s)", st, c, actual, ctx.BestPred[st]))
+}
+
+// dropExceptions removes the exceptions and evidence of an evicted structure.
+func dropExceptions(ctx *Context, st string) {
+	for _, key := range sortedKeys(ctx.Exceptions) {
+		if ctx.Exceptions[key].Struct == st {
+			delete(ctx.Exceptions, key)
+		}
+	}
+	delete(ctx.BaseHitCtx, st)
+	for _, ek := range sortedKeys(ctx.ExceptEvidence) {
+		if strings.HasPrefix(ek, st+"|") {
+			delete(ctx.ExceptEvidence, ek)
+		}
+	}
+}
+
+// exceptionList renders the strongest exceptions for the board.
+func exceptionList(ctx *Context, n int) []string {
+	keys := sortedKeys(ctx.Exceptions)
+	sort.SliceStable(keys, func(i, j int) bool { return ctx.Exceptions[keys[i]].Conf > ctx.Exceptions[keys[j]].Conf })
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		ex := ctx.Exceptions[k]
+		out = append(out, fmt.Sprintf("%s after %s ⇒ %s (base %s) conf=%.2f hits=%d misses=%d",
+			ex.Struct, ex.Context, ex.Tok, ctx.BestPred[ex.Struct], ex.Conf, ex.Hits, ex.Misses))
+	}
+	return out
+}
+
+// cmdExcept handles: except [clear]
+func cmdExcept(ctx *Context, args []string) {
+	if len(args) == 1 && args[0] == "clear" {
+		clear(ctx.Exceptions)
+		clear(ctx.ExceptEvidence)
+		clear(ctx.BaseHitCtx)
+		journalf(ctx, "EXCEPT cleared")
+		fmt.Println("Exceptions cleared")
+		return
+	}
+	if len(ctx.Exceptions) == 0 {
+		fmt.Println("No exceptions learned")
+		return
+	}
+	for _, e := range exceptionList(ctx, len(ctx.Exceptions)) {
+		fmt.Printf("Exception: %s\n", e)
+	}
+}
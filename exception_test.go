@@ -0,0 +1,94 @@
+package main
+
+import "testing"
+
+func TestExceptionLearning(t *testing.T) {
+	const st = "[1-2]"
+	tests := []struct {
+		name     string
+		hitCtx   string // context the base prediction was confirmed in before each error; "" = none
+		learning bool
+		errors   int
+		want     bool
+	}{
+		{"recurring contextual error", "5", true, 2, true},
+		{"single error", "5", true, 1, false},
+		{"no contrast", "", true, 3, false},
+		{"same context", "0", true, 3, false},
+		{"learning off", "5", false, 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			ctx.LearningEnabled = tt.learning
+			ctx.BestPred[st] = "3"
+			for i := 0; i < tt.errors; i++ {
+				ctx.ExpectCtx[st] = "0"
+				if tt.hitCtx != "" {
+					ctx.BaseHitCtx[st] = tt.hitCtx
+				}
+				exceptionMiss(ctx, st, "3", "4")
+			}
+			ex, ok := ctx.Exceptions[exceptKey(st, "0")]
+			if ok != tt.want {
+				t.Fatalf("learned = %v, want %v (evidence %v)", ok, tt.want, ctx.ExceptEvidence)
+			}
+			if ok && (ex.Tok != "4" || ex.Conf != exceptInitCnf) {
+				t.Errorf("exception = %+v, want ⇒ 4 at conf %.2f", *ex, exceptInitCnf)
+			}
+		})
+	}
+}
+
+func TestExceptionLifecycle(t *testing.T) {
+	const st = "[1-2]"
+	ctx := NewContext()
+	ctx.Tick = 10
+	ctx.BestPred[st] = "3"
+	ctx.SensLog = []TimedTok{{Tick: 8, Tok: "0"}, {Tick: 9, Tok: "1"}, {Tick: 10, Tok: "2"}}
+	ex := &Exception{Struct: st, Context: "0", Tok: "4", Conf: exceptInitCnf}
+	ctx.Exceptions[exceptKey(st, "0")] = ex
+
+	if got := applyException(ctx, st, "3"); got != "4" {
+		t.Fatalf("applyException after 0 = %s, want 4", got)
+	}
+	exceptionHit(ctx, st, "4")
+	if ex.Hits != 1 || ex.Conf <= exceptInitCnf {
+		t.Errorf("hit not credited: %+v", *ex)
+	}
+
+	// Misses weaken the exception until it no longer overrides, then drop it.
+	overrides := true
+	for i := 0; i < 20 && ctx.Exceptions[exceptKey(st, "0")] != nil; i++ {
+		ctx.ExpectCtx[st] = "0"
+		exceptionMiss(ctx, st, "4", "3")
+		if ex.Conf < exceptMinConf {
+			overrides = false
+		}
+	}
+	if _, ok := ctx.Exceptions[exceptKey(st, "0")]; ok {
+		t.Fatalf("exception kept at conf %.2f", ex.Conf)
+	}
+	if overrides {
+		t.Errorf("exception was dropped before it stopped overriding")
+	}
+	if got := applyException(ctx, st, "3"); got != "3" {
+		t.Errorf("applyException after drop = %s, want 3", got)
+	}
+}
+
+func TestDropExceptions(t *testing.T) {
+	ctx := NewContext()
+	ctx.Exceptions[exceptKey("[1-2]", "0")] = &Exception{Struct: "[1-2]", Context: "0", Tok: "4"}
+	ctx.Exceptions[exceptKey("[2-3]", "0")] = &Exception{Struct: "[2-3]", Context: "0", Tok: "4"}
+	ctx.ExceptEvidence[exceptKey("[1-2]", "5")+"|4"] = 0.5
+	ctx.BaseHitCtx["[1-2]"] = "5"
+
+	dropExceptions(ctx, "[1-2]")
+	if len(ctx.Exceptions) != 1 || ctx.Exceptions[exceptKey("[2-3]", "0")] == nil {
+		t.Errorf("exceptions = %v, want only [2-3]", sortedKeys(ctx.Exceptions))
+	}
+	if len(ctx.ExceptEvidence) != 0 || len(ctx.BaseHitCtx) != 0 {
+		t.Errorf("evidence %v / contrast %v left", ctx.ExceptEvidence, ctx.BaseHitCtx)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ForgetPolicy decides which learned blocks of one type are pruned.
+// Select receives the candidate block IDs in sorted order and returns the
+// ones to remove. Structures active in this or the previous tick are
+// filtered out before Select is called.
+type ForgetPolicy interface {
+	Name() string
+	Select(ctx *Context, ids []string) []string
+}
+
+// AgePolicy is the original rule: prune blocks that have not fired for
+// After ticks, unless the structure still predicts with confidence >= MinConf
+// or holds a transition weight >= MinWeight.
+type AgePolicy struct {
+	After     int
+	MinConf   float64
+	MinWeight float64
+}
+
+func (p AgePolicy) Name() string { return fmt.Sprintf("age(after=%d)", p.After) }
+
+func (p AgePolicy) Select(ctx *Context, ids []string) []string {
+	if p.After <= 0 {
+		return nil
+	}
+	out := make([]string, 0, 4)
+	for _, id := range ids {
+		last, ok := ctx.BlockLastFire[id]
+		if !ok || ctx.Tick-last < p.After {
+			continue
+		}
+		if p.useful(ctx, structOfID(id)) {
+			continue
+		}
+		out = append(out, id)
+	}
+	return out
+}
+
+func (p AgePolicy) useful(ctx *Context, st string) bool {
+	if ctx.BestPred[st] != "" && ctx.PredConf[st] >= p.MinConf {
+		return true
+	}
+	for _, w := range ctx.TransCounts[st] {
+		if w >= p.MinWeight {
+			return true
+		}
+	}
+	return false
+}
+
+// LRUPolicy keeps the Keep most recently fired blocks and prunes the rest.
+type LRUPolicy struct{ Keep int }
+
+func (p LRUPolicy) Name() string { return fmt.Sprintf("lru(keep=%d)", p.Keep) }
+
+func (p LRUPolicy) Select(ctx *Context, ids []string) []string {
+	return keepTop(ids, p.Keep, func(id string) float64 {
+		if last, ok := ctx.BlockLastFire[id]; ok {
+			return float64(last)
+		}
+		return math.Inf(-1)
+	})
+}
+
+// LFUPolicy keeps the Keep most frequently fired blocks and prunes the rest.
+type LFUPolicy struct{ Keep int }
+
+func (p LFUPolicy) Name() string { return fmt.Sprintf("lfu(keep=%d)", p.Keep) }
+
+func (p LFUPolicy) Select(ctx *Context, ids []string) []string {
+	return keepTop(ids, p.Keep, func(id string) float64 { return float64(ctx.FireCount[id]) })
+}
+
+// UtilityPolicy prunes blocks whose Utility falls below Min.
+type UtilityPolicy struct{ Min float64 }
+
+func (p UtilityPolicy) Name() string { return fmt.Sprintf("utility(min=%.2f)", p.Min) }
+
+func (p UtilityPolicy) Select(ctx *Context, ids []string) []string {
+	out := make([]string, 0, 4)
+	for _, id := range ids {
+		if Utility(ctx, id) < p.Min {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// ConfDecayPolicy lets prediction confidence fade with idle time:
+// PredConf * 0.5^(idle/HalfLife). Blocks whose decayed confidence falls
+// below Min are pruned.
+type ConfDecayPolicy struct {
+	HalfLife int
+	Min      float64
+}
+
+func (p ConfDecayPolicy) Name() string {
+	return fmt.Sprintf("confdecay(halflife=%d,min=%.2f)", p.HalfLife, p.Min)
+}
+
+func (p ConfDecayPolicy) Select(ctx *Context, ids []string) []string {
+	if p.HalfLife <= 0 {
+		return nil
+	}
+	out := make([]string, 0, 4)
+	for _, id := range ids {
+		last, ok := ctx.BlockLastFire[id]
+		if !ok {
+			continue
+		}
+		idle := float64(ctx.Tick - last)
+		if ctx.PredConf[structOfID(id)]*math.Pow(0.5, idle/float64(p.HalfLife)) < p.Min {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// keepTop keeps the keep highest-scoring ids (ties by ID) and returns the rest.
+func keepTop(ids []string, keep int, score func(string) float64) []string {
+	if keep < 0 || len(ids) <= keep {
+		return nil
+	}
+	sorted := append([]string(nil), ids...)
+	sort.SliceStable(sorted, func(i, j int) bool { return score(sorted[i]) > score(sorted[j]) })
+	return sorted[keep:]
+}
+
+// forgetTypes maps config names to learned block prefixes.
+var forgetTypes = map[string]string{
+	"pair":    "COACT:",
+	"seq":     "SEQ:",
+	"compose": "COMPOSE:",
+	"gap":     "GAP:",
+	"rhythm":  "RHYTHM:",
+}
+
+// defaultForgetPolicy is the age rule driven by ctx.ForgetAfter.
+func defaultForgetPolicy(ctx *Context) ForgetPolicy {
+	return AgePolicy{After: ctx.ForgetAfter, MinConf: 0.30, MinWeight: 0.20}
+}
+
+// policyFor returns the policy configured for a block prefix, falling back
+// to ctx.ForgetPolicy and then to the age rule.
+func policyFor(ctx *Context, prefix string) ForgetPolicy {
+	if p, ok := ctx.ForgetPolicies[prefix]; ok {
+		return p
+	}
+	if ctx.ForgetPolicy != nil {
+		return ctx.ForgetPolicy
+	}
+	return defaultForgetPolicy(ctx)
+}
+
+// forgetCandidates returns the sorted learned blocks of one type that may be
+// pruned: structures active in this or the previous tick and pinned ones are always kept.
+func forgetCandidates(ctx *Context, prefix string) []string {
+	ids := make([]string, 0, 16)
+	for _, id := range sortedKeys(ctx.Blocks) {
+		if !strings.HasPrefix(id, prefix) {
+			continue
+		}
+		st := structOfID(id)
+		if ctx.ThisStructSet[st] || ctx.PrevStructSet[st] || ctx.Pinned[st] {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// parseForgetPolicy builds a policy from a name and an optional parameter.
+func parseForgetPolicy(ctx *Context, name string, param string) (ForgetPolicy, error) {
+	num := func(def float64) (float64, error) {
+		if param == "" {
+			return def, nil
+		}
+		return strconv.ParseFloat(param, 64)
+	}
+	switch name {
+	case "age":
+		v, err := num(float64(ctx.ForgetAfter))
+		return AgePolicy{After: int(v), MinConf: 0.30, MinWeight: 0.20}, err
+	case "lru":
+		v, err := num(16)
+		return LRUPolicy{Keep: int(v)}, err
+	case "lfu":
+		v, err := num(16)
+		return LFUPolicy{Keep: int(v)}, err
+	case "utility":
+		v, err := num(0.5)
+		return UtilityPolicy{Min: v}, err
+	case "confdecay":
+		v, err := num(60)
+		return ConfDecayPolicy{HalfLife: int(v), Min: 0.10}, err
+	}
+	return nil, fmt.Errorf("unknown policy %q (age|lru|lfu|utility|confdecay)", name)
+}
+
+// cmdForget handles:
+//
+//	forget                                 show the configuration
+//	forget <all|pair|seq|compose|gap|rhythm> <policy> [param]
+//	forget dry                             list what every policy would prune now
+func cmdForget(ctx *Context, args []string) {
+	types := sortedKeys(forgetTypes)
+
+	if len(args) == 1 && args[0] == "dry" {
+		cprintf(C_MAGENTA+C_BOLD, "FORGET DRY RUN t=%03d (nothing is removed)\n", ctx.Tick)
+		for _, name := range []string{"age", "lru", "lfu", "utility", "confdecay"} {
+			p, _ := parseForgetPolicy(ctx, name, "")
+			var all []string
+			for _, t := range types {
+				all = append(all, p.Select(ctx, forgetCandidates(ctx, forgetTypes[t]))...)
+			}
+			fmt.Printf("  %-32s would prune %d: %v\n", p.Name(), len(all), all)
+		}
+		var cur []string
+		for _, t := range types {
+			cur = append(cur, policyFor(ctx, forgetTypes[t]).Select(ctx, forgetCandidates(ctx, forgetTypes[t]))...)
+		}
+		fmt.Printf("  %-32s would prune %d: %v\n", "(configured)", len(cur), cur)
+		return
+	}
+
+	if len(args) >= 2 {
+		p, err := parseForgetPolicy(ctx, args[1], strings.Join(args[2:], ""))
+		if err != nil {
+			fmt.Println("forget:", err)
+			return
+		}
+		if args[0] == "all" {
+			ctx.ForgetPolicy = p
+			ctx.ForgetPolicies = nil
+		} else if prefix, ok := forgetTypes[args[0]]; ok {
+			if ctx.ForgetPolicies == nil {
+				ctx.ForgetPolicies = make(map[string]ForgetPolicy)
+			}
+			ctx.ForgetPolicies[prefix] = p
+		} else {
+			fmt.Printf("forget: unknown block type %q (all|%s)\n", args[0], strings.Join(types, "|"))
+			return
+		}
+		journalf(ctx, "FORGET %s=%s", args[0], p.Name())
+	} else if len(args) == 1 {
+		fmt.Println("usage: forget [<all|pair|seq|compose|gap|rhythm> <age|lru|lfu|utility|confdecay> [param]] | forget dry")
+		return
+	}
+
+	for _, t := range types {
+		fmt.Printf("Forget %-8s %s\n", t, policyFor(ctx, forgetTypes[t]).Name())
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestForgetPolicySelect(t *testing.T) {
+	ctx := NewContext()
+	ctx.Tick = 1000
+	blocks := []struct {
+		st   string
+		last int
+		fire int
+		conf float64
+	}{
+		{"[1-2]", 800, 5, 0},
+		{"[1-3]", 800, 1, 0.8},
+		{"[1-4]", 950, 3, 0.4},
+	}
+	ids := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		id := "COACT:" + b.st
+		ids = append(ids, id)
+		ctx.BlockLastFire[id] = b.last
+		ctx.FireCount[id] = b.fire
+		if b.conf > 0 {
+			ctx.BestPred[b.st] = "9"
+			ctx.PredConf[b.st] = b.conf
+		}
+	}
+
+	tests := []struct {
+		policy ForgetPolicy
+		want   []string
+	}{
+		{AgePolicy{After: 100, MinConf: 0.5, MinWeight: 1}, []string{"COACT:[1-2]"}},
+		{AgePolicy{After: 0}, nil},
+		{LRUPolicy{Keep: 1}, []string{"COACT:[1-2]", "COACT:[1-3]"}},
+		{LRUPolicy{Keep: 0}, []string{"COACT:[1-4]", "COACT:[1-2]", "COACT:[1-3]"}},
+		{LRUPolicy{Keep: 3}, nil},
+		{LFUPolicy{Keep: 1}, []string{"COACT:[1-4]", "COACT:[1-3]"}},
+		{ConfDecayPolicy{HalfLife: 100, Min: 0.25}, []string{"COACT:[1-2]", "COACT:[1-3]"}},
+		{ConfDecayPolicy{HalfLife: 0, Min: 1}, nil},
+		{UtilityPolicy{Min: math.Inf(-1)}, nil},
+		{UtilityPolicy{Min: math.Inf(1)}, ids},
+	}
+	for _, tt := range tests {
+		got := tt.policy.Select(ctx, ids)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s selected %v, want %v", tt.policy.Name(), got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GenStep is one token produced by a generative rollout.
+type GenStep struct {
+	Token  string  // token fed back into the field as K_SENS
+	Struct string  // structure whose expectation produced the token
+	Conf   float64 // confidence of the step (PredConf when greedy, sample probability otherwise)
+}
+
+// GenerateOptions controls how the next token is chosen during a rollout.
+type GenerateOptions struct {
+	Sample bool  // draw from the transition distribution instead of taking BestPred
+	Seed   int64 // RNG seed used when Sample is set
+}
+
+// Generate runs a free-running rollout.
+// The prefix primes the field, then for n steps the field's own expectation
+// is fed back as the next K_SENS. The rollout runs with learning disabled on
+// a copy of the context (see cloneContext), so it leaves no trace in ctx
+// (inhibition, energy, cooldowns, pruning, episode boundary, clock, random
+// stream). The rollout stops early when nothing is expected.
+func Generate(ctx *Context, prefix []string, n int, opt GenerateOptions) []GenStep {
+	gen := cloneContext(ctx)
+	gen.LearningEnabled, gen.LearnStruct, gen.LearnPred = false, false, false
+	if ctx.Rand != nil {
+		gen.Rand = rand.New(rand.NewSource(opt.Seed))
+	}
+
+	rng := rand.New(rand.NewSource(opt.Seed))
+
+	resetEpisodeBoundary(gen)
+	for _, tok := range prefix {
+		feedToken(gen, tok)
+	}
+
+	steps := make([]GenStep, 0, n)
+	for i := 0; i < n; i++ {
+		st, tok, conf, ok := nextGenToken(gen, opt, rng)
+		if !ok {
+			break
+		}
+		steps = append(steps, GenStep{Token: tok, Struct: st, Conf: conf})
+		feedToken(gen, tok)
+	}
+	return steps
+}
+
+// feedToken runs one tick with tok as the only sensory input, without any logging.
+func feedToken(ctx *Context, tok string) []Signal {
+	return feedItem(ctx, tok, "USER")
+}
+
+// feedItem runs one tick for an input item ("a" or "a+b"), without any logging.
+func feedItem(ctx *Context, item, from string) []Signal {
+	parts := splitSimul(item)
+	in := make([]Signal, 0, len(parts))
+	for _, p := range parts {
+		ensureSensor(ctx, p)
+		in = append(in, Signal{Kind: K_SENS, Value: p, Mass: 1.0, Time: ctx.Tick, From: from})
+	}
+	return RunTick(ctx, in)
+}
+
+// nextGenToken picks the structure that drives the next step and the token it expects.
+// The armed expectation is preferred; otherwise the most confident structure
+// active in the last tick is used.
+func nextGenToken(ctx *Context, opt GenerateOptions, rng *rand.Rand) (st, tok string, conf float64, ok bool) {
+	cands := make([]string, 0, len(ctx.PendingExpect))
+	for s, t := range ctx.PendingExpect {
+		if t != "" {
+			cands = append(cands, s)
+		}
+	}
+	if len(cands) == 0 {
+		for s := range ctx.PrevStructSet {
+			if ctx.BestPred[s] != "" {
+				cands = append(cands, s)
+			}
+		}
+	}
+	if len(cands) == 0 {
+		return "", "", 0, false
+	}
+	sort.Slice(cands, func(i, j int) bool {
+		ci, cj := ctx.PredConf[cands[i]], ctx.PredConf[cands[j]]
+		if ci != cj {
+			return ci > cj
+		}
+		return preferStructName(cands[i], cands[j])
+	})
+	st = cands[0]
+
+	if !opt.Sample {
+		tok = ctx.PendingExpect[st]
+		if tok == "" {
+			tok = ctx.BestPred[st]
+		}
+		return st, tok, ctx.PredConf[st], true
+	}
+
+	dist := ctx.TransCounts[st]
+	toks := make([]string, 0, len(dist))
+	sum := 0.0
+	for _, t := range sortedKeys(dist) {
+		if w := dist[t]; w > 0 {
+			toks = append(toks, t)
+			sum += w
+		}
+	}
+	if sum <= 0 {
+		return st, ctx.BestPred[st], ctx.PredConf[st], true
+	}
+
+	r := rng.Float64() * sum
+	for _, t := range toks {
+		r -= dist[t]
+		if r < 0 {
+			return st, t, dist[t] / sum, true
+		}
+	}
+	last := toks[len(toks)-1]
+	return st, last, dist[last] / sum, true
+}
+
+// cmdGenerate handles: generate [-sample] [-seed=N] <prefix tokens...> <n>
+func cmdGenerate(ctx *Context, args []string) {
+	opt := GenerateOptions{}
+	rest := make([]string, 0, len(args))
+	for _, a := range args {
+		switch {
+		case a == "-sample":
+			opt.Sample = true
+		case strings.HasPrefix(a, "-seed="):
+			v, err := strconv.ParseInt(strings.TrimPrefix(a, "-seed="), 10, 64)
+			if err != nil {
+				fmt.Printf("generate: bad seed %q\n", a)
+				return
+			}
+			opt.Seed = v
+		default:
+			rest = append(rest, a)
+		}
+	}
+	if len(rest) < 2 {
+		fmt.Println("usage: generate [-sample] [-seed=N] <prefix tokens...> <n>")
+		return
+	}
+	n, err := strconv.Atoi(rest[len(rest)-1])
+	if err != nil || n <= 0 {
+		fmt.Printf("generate: bad step count %q\n", rest[len(rest)-1])
+		return
+	}
+	prefix := rest[:len(rest)-1]
+
+	mode := "greedy"
+	if opt.Sample {
+		mode = fmt.Sprintf("sample seed=%d", opt.Seed)
+	}
+	cprintf(C_MAGENTA+C_BOLD, "GENERATE: prefix=%v steps=%d mode=%s (learning off)\n", prefix, n, mode)
+
+	steps := Generate(ctx, prefix, n, opt)
+	toks := make([]string, 0, len(steps))
+	for i, g := range steps {
+		fmt.Printf("           g%02d %s  via %s conf=%.2f\n", i+1, g.Token, g.Struct, g.Conf)
+		toks = append(toks, g.Token)
+	}
+	if len(steps) < n {
+		cprintf(C_GRAY, "           (stopped after %d steps: no expectation armed)\n", len(steps))
+	}
+	cprintf(C_CYAN+C_BOLD, "GENERATED: %s\n", strings.Join(toks, " "))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGenerateLeavesContextUnchanged(t *testing.T) {
+	tests := []struct {
+		name string
+		seed bool // stochastic mode on the context
+		opt  GenerateOptions
+	}{
+		{"greedy", false, GenerateOptions{}},
+		{"sample", false, GenerateOptions{Sample: true, Seed: 3}},
+		{"stochastic context", true, GenerateOptions{Sample: true, Seed: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			train := func() *Context {
+				ctx := NewContext()
+				if tt.seed {
+					SetSeed(ctx, 11)
+					ctx.Temperature = 0.3
+				}
+				trainCycle(ctx, []string{"1", "2", "3", "temp=21", "temp=27"}, 10)
+				return ctx
+			}
+			ctx, twin := train(), train()
+			before := Digest(ctx)
+
+			steps := Generate(ctx, []string{"1", "2"}, 6, tt.opt)
+			if len(steps) == 0 {
+				t.Fatal("nothing generated")
+			}
+			if got := Digest(ctx); got != before {
+				t.Errorf("digest changed from %s to %s", before, got)
+			}
+			if again := Generate(ctx, []string{"1", "2"}, 6, tt.opt); !slices.Equal(again, steps) {
+				t.Errorf("second rollout %v differs from %v", again, steps)
+			}
+
+			// The context goes on exactly as if nothing had been generated.
+			for _, tok := range []string{"1", "2", "3"} {
+				feedToken(ctx, tok)
+				feedToken(twin, tok)
+			}
+			if Digest(ctx) != Digest(twin) {
+				t.Errorf("context diverged from its twin after the rollout")
+			}
+		})
+	}
+}
+
+func TestCloneContextIsIndependent(t *testing.T) {
+	ctx := NewContext()
+	trainCycle(ctx, []string{"1", "2", "temp=21", "temp=39"}, 6)
+	f := numFieldByToken(ctx, "temp@20..30")
+	if f == nil {
+		t.Fatal("no receptive field for temp=21")
+	}
+	f.errVals = append(f.errVals, 21)
+
+	c := cloneContext(ctx)
+	cf := numFieldByToken(c, "temp@20..30")
+	if cf == f || c.Blocks[f.ID()] != Block(cf) {
+		t.Fatal("range sensor not cloned once for Blocks and NumFields")
+	}
+	cf.errVals[0] = 99
+	cf.sum += 100
+	if f.errVals[0] == 99 || f.sum == cf.sum {
+		t.Errorf("clone shares block state with the original")
+	}
+}
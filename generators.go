@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Generator blocks emit signals on their own schedule, without external input.
+// They are configured by hand (REPL or scenario), never learned, and never pruned.
+
+// OscillatorBlock emits value every period ticks, offset by phase.
+type OscillatorBlock struct {
+	name   string
+	period int
+	phase  int
+	kind   Kind
+	value  string
+	mass   float64
+}
+
+func NewOscillatorBlock(name string, period, phase int, value string) *OscillatorBlock {
+	if period < 1 {
+		period = 1
+	}
+	return &OscillatorBlock{name: name, period: period, phase: phase, kind: K_ACT, value: value, mass: 1.0}
+}
+
+func (b *OscillatorBlock) ID() string { return "OSC:" + b.name }
+
+func (b *OscillatorBlock) Clone() Block { c := *b; return &c }
+
+func (b *OscillatorBlock) React(s Signal, ctx *Context) []Signal { return nil }
+
+func (b *OscillatorBlock) Tick(ctx *Context) []Signal {
+	if (ctx.Tick-b.phase)%b.period != 0 {
+		return nil
+	}
+	return []Signal{{Kind: b.kind, Value: b.value, Mass: b.mass, Time: ctx.Tick, From: b.ID()}}
+}
+
+// TimerBlock is a one-shot timer. A signal whose value equals trigger arms it
+// and schedules value for delivery delay ticks later. Triggers while armed are ignored.
+type TimerBlock struct {
+	name    string
+	trigger string
+	delay   int
+	kind    Kind
+	value   string
+	armedAt int // -1 when idle
+}
+
+func NewTimerBlock(name, trigger string, delay int, value string) *TimerBlock {
+	if delay < 1 {
+		delay = 1
+	}
+	return &TimerBlock{name: name, trigger: trigger, delay: delay, kind: K_ACT, value: value, armedAt: -1}
+}
+
+func (b *TimerBlock) ID() string { return "TIMER:" + b.name }
+
+func (b *TimerBlock) Clone() Block { c := *b; return &c }
+
+func (b *TimerBlock) React(s Signal, ctx *Context) []Signal {
+	if s.Value == b.trigger && s.From != b.ID() && b.armedAt < 0 {
+		b.armedAt = ctx.Tick
+		ctx.Schedule(Signal{Kind: b.kind, Value: b.value, Mass: 1.0, From: b.ID()}, b.delay)
+	}
+	return nil
+}
+
+// Tick only re-opens the timer once its scheduled signal has been delivered.
+func (b *TimerBlock) Tick(ctx *Context) []Signal {
+	if b.armedAt >= 0 && ctx.Tick-b.armedAt >= b.delay {
+		b.armedAt = -1
+	}
+	return nil
+}
+
+// DriveGenBlock is an internal drive. Its level grows by rate every tick
+// (and by the mass of any signal whose value equals source); on reaching
+// threshold it emits a K_DRIVE signal named after the drive and resets.
+type DriveGenBlock struct {
+	name      string
+	rate      float64
+	threshold float64
+	source    string
+	level     float64
+}
+
+func NewDriveGenBlock(name string, rate, threshold float64, source string) *DriveGenBlock {
+	return &DriveGenBlock{name: name, rate: rate, threshold: threshold, source: source}
+}
+
+func (b *DriveGenBlock) ID() string { return "DRIVEGEN:" + b.name }
+
+func (b *DriveGenBlock) Clone() Block { c := *b; return &c }
+
+func (b *DriveGenBlock) React(s Signal, ctx *Context) []Signal {
+	if b.source != "" && s.Value == b.source && s.From != b.ID() {
+		b.level += s.Mass
+	}
+	return nil
+}
+
+func (b *DriveGenBlock) Tick(ctx *Context) []Signal {
+	b.level += b.rate
+	if b.level < b.threshold {
+		return nil
+	}
+	mass := b.level
+	b.level = 0
+	return []Signal{{Kind: K_DRIVE, Value: strings.ToUpper(b.name), Mass: mass, Time: ctx.Tick, From: b.ID()}}
+}
+
+var generatorPrefixes = []string{"OSC:", "TIMER:", "DRIVEGEN:"}
+
+func isGeneratorID(id string) bool {
+	for _, p := range generatorPrefixes {
+		if strings.HasPrefix(id, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func describeGenerator(b Block) string {
+	switch g := b.(type) {
+	case *OscillatorBlock:
+		return fmt.Sprintf("osc %s period=%d phase=%d emits %s:%s", g.name, g.period, g.phase, g.kind, g.value)
+	case *TimerBlock:
+		state := "idle"
+		if g.armedAt >= 0 {
+			state = fmt.Sprintf("armed@t=%d", g.armedAt)
+		}
+		return fmt.Sprintf("timer %s on %q after %d emits %s:%s (%s)", g.name, g.trigger, g.delay, g.kind, g.value, state)
+	case *DriveGenBlock:
+		return fmt.Sprintf("drive %s rate=%.2f threshold=%.2f source=%q level=%.2f", g.name, g.rate, g.threshold, g.source, g.level)
+	}
+	return b.ID()
+}
+
+// cmdGen handles:
+//
+//	gen osc <name> <period> [value] [phase]
+//	gen timer <name> <trigger> <delay> [value]
+//	gen drive <name> <rate> <threshold> [source]
+//	gen list | gen del <name>
+func cmdGen(ctx *Context, args []string) {
+	usage := func() {
+		fmt.Println("usage: gen osc <name> <period> [value] [phase] | gen timer <name> <trigger> <delay> [value]")
+		fmt.Println("       gen drive <name> <rate> <threshold> [source] | gen list | gen del <name>")
+	}
+	if len(args) == 0 {
+		usage()
+		return
+	}
+
+	switch args[0] {
+	case "list":
+		ids := make([]string, 0, 4)
+		for id := range ctx.Blocks {
+			if isGeneratorID(id) {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		if len(ids) == 0 {
+			fmt.Println("Generators: (none)")
+		}
+		for _, id := range ids {
+			fmt.Printf("Generator %s\n", describeGenerator(ctx.Blocks[id]))
+		}
+		return
+
+	case "del":
+		if len(args) < 2 {
+			usage()
+			return
+		}
+		for _, p := range generatorPrefixes {
+			if _, ok := ctx.Blocks[p+args[1]]; ok {
+				removeBlock(ctx, p+args[1])
+				fmt.Printf("Generator %s removed\n", args[1])
+				return
+			}
+		}
+		fmt.Printf("gen: no generator named %q\n", args[1])
+		return
+
+	case "osc":
+		if len(args) < 3 {
+			usage()
+			return
+		}
+		period, err := strconv.Atoi(args[2])
+		if err != nil || period < 1 {
+			fmt.Printf("gen: bad period %q\n", args[2])
+			return
+		}
+		value := args[1]
+		if len(args) > 3 {
+			value = args[3]
+		}
+		phase := 0
+		if len(args) > 4 {
+			if phase, err = strconv.Atoi(args[4]); err != nil {
+				fmt.Printf("gen: bad phase %q\n", args[4])
+				return
+			}
+		}
+		addGenerator(ctx, NewOscillatorBlock(args[1], period, phase, value))
+
+	case "timer":
+		if len(args) < 4 {
+			usage()
+			return
+		}
+		delay, err := strconv.Atoi(args[3])
+		if err != nil || delay < 1 {
+			fmt.Printf("gen: bad delay %q\n", args[3])
+			return
+		}
+		value := args[1]
+		if len(args) > 4 {
+			value = args[4]
+		}
+		addGenerator(ctx, NewTimerBlock(args[1], args[2], delay, value))
+
+	case "drive":
+		if len(args) < 4 {
+			usage()
+			return
+		}
+		rate, err1 := strconv.ParseFloat(args[2], 64)
+		threshold, err2 := strconv.ParseFloat(args[3], 64)
+		if err1 != nil || err2 != nil || threshold <= 0 {
+			fmt.Printf("gen: bad rate/threshold %q %q\n", args[2], args[3])
+			return
+		}
+		source := ""
+		if len(args) > 4 {
+			source = args[4]
+		}
+		addGenerator(ctx, NewDriveGenBlock(args[1], rate, threshold, source))
+
+	default:
+		usage()
+	}
+}
+
+func addGenerator(ctx *Context, b Block) {
+	if _, exists := ctx.Blocks[b.ID()]; exists {
+		removeBlock(ctx, b.ID())
+	}
+	ctx.AddBlock(b)
+	fmt.Printf("Generator %s\n", describeGenerator(b))
+}
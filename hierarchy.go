@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Inhibition kernels decide which losing structures the winner inhibits.
+//
+//	global        every other active structure (the original rule)
+//	overlap       only structures sharing a token with the winner
+//	hierarchical  overlap, plus explaining away: an active composite absorbs
+//	              part of its components' mass and inhibits them top-down, and
+//	              the most specific predicting structure is preferred as winner
+const (
+	KernelGlobal       = "global"
+	KernelOverlap      = "overlap"
+	KernelHierarchical = "hierarchical"
+)
+
+const (
+	hierAbsorb   = 0.5 // share of a component's mass absorbed by its composite
+	hierInhib    = 0.5 // top-down inhibition of an explained-away component
+	hierMinShare = 0.5 // a specific structure must reach this share of the top mass to win
+)
+
+// structMembers returns the sensory tokens a structure is built from,
+// resolved through its block; nil for unknown structures.
+func structMembers(ctx *Context, st string) []string {
+	for _, p := range learnedPrefixes {
+		switch b := ctx.Blocks[p+st].(type) {
+		case *CoActBlock:
+			return []string{b.a, b.b}
+		case *SeqBlock:
+			return []string{b.a, b.b}
+		case *GapSeqBlock:
+			return []string{b.a, b.b}
+		case *RhythmBlock:
+			return []string{b.tok}
+		case *ComposeBlock:
+			return uniqueSorted(append(structMembers(ctx, b.base), b.x))
+		}
+	}
+	return nil
+}
+
+// overlaps reports whether two structures share a token.
+func overlaps(ctx *Context, a, b string) bool {
+	mb := structMembers(ctx, b)
+	for _, t := range structMembers(ctx, a) {
+		if containsStr(mb, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// isComponentOf reports whether c is a composition built on k, directly
+// or further down its chain of ComposeBlock bases.
+func isComponentOf(ctx *Context, k, c string) bool {
+	for {
+		cb, ok := ctx.Blocks["COMPOSE:"+c].(*ComposeBlock)
+		if !ok {
+			return false
+		}
+		if cb.base == k {
+			return true
+		}
+		c = cb.base
+	}
+}
+
+// explainAway lets active composites absorb their active components
+// (hierarchical kernel only). Each component is credited to its most
+// specific active composite; the result is kept in ctx.ExplainedAway.
+func explainAway(ctx *Context) {
+	clearStringMap(ctx.ExplainedAway)
+	if ctx.InhibKernel != KernelHierarchical || len(ctx.ThisStructMass) < 2 {
+		return
+	}
+	sts := sortedKeys(ctx.ThisStructMass)
+	sort.SliceStable(sts, func(i, j int) bool {
+		return len(structMembers(ctx, sts[i])) > len(structMembers(ctx, sts[j]))
+	})
+	for _, c := range sts {
+		if _, absorbed := ctx.ExplainedAway[c]; absorbed {
+			continue
+		}
+		for _, k := range sts {
+			if _, done := ctx.ExplainedAway[k]; done || !isComponentOf(ctx, k, c) {
+				continue
+			}
+			m := ctx.ThisStructMass[k] * hierAbsorb
+			ctx.ThisStructMass[k] -= m
+			ctx.ThisStructMass[c] += m
+			ctx.Inhib[k] += hierInhib
+			ctx.ExplainedAway[k] = c
+		}
+	}
+}
+
+// specificWinner picks, under the hierarchical kernel, the most specific
+// structure that predicts with confidence >= 0.25 and carries at least
+// hierMinShare of the top mass. It returns "" when no structure qualifies.
+func specificWinner(ctx *Context) (string, float64) {
+	top := 0.0
+	for _, m := range ctx.ThisStructMass {
+		if m > top {
+			top = m
+		}
+	}
+	winner, wMass, wSize := "", 0.0, 0
+	for _, st := range sortedKeys(ctx.ThisStructMass) {
+		m := ctx.ThisStructMass[st]
+		if ctx.BestPred[st] == "" || ctx.PredConf[st] < 0.25 || m < top*hierMinShare {
+			continue
+		}
+		size := len(structMembers(ctx, st))
+		if winner == "" || size > wSize ||
+			(size == wSize && (m > wMass || (m == wMass && preferStructName(st, winner)))) {
+			winner, wMass, wSize = st, m, size
+		}
+	}
+	return winner, wMass
+}
+
+// inhibitLosers applies the configured kernel to the structures that lost
+// against winner.
+func inhibitLosers(ctx *Context, winner string, wMass float64) {
+	if winner == "" || len(ctx.ThisStructMass) < 2 {
+		return
+	}
+	for _, st := range sortedKeys(ctx.ThisStructMass) {
+		if st == winner {
+			continue
+		}
+		if ctx.InhibKernel != KernelGlobal && ctx.InhibKernel != "" && !overlaps(ctx, st, winner) {
+			continue
+		}
+		add := 0.7
+		if wMass-ctx.ThisStructMass[st] > 0.5 {
+			add = 1.0
+		}
+		ctx.Inhib[st] += add
+	}
+}
+
+// explainedSummary renders "component<-composite" pairs for the board.
+func explainedSummary(ctx *Context) []string {
+	out := make([]string, 0, len(ctx.ExplainedAway))
+	for _, k := range sortedKeys(ctx.ExplainedAway) {
+		out = append(out, k+"<-"+ctx.ExplainedAway[k])
+	}
+	return out
+}
+
+// cmdKernel handles: kernel [global|overlap|hierarchical]
+func cmdKernel(ctx *Context, args []string) {
+	if len(args) == 1 {
+		switch args[0] {
+		case KernelGlobal, KernelOverlap, KernelHierarchical:
+			ctx.InhibKernel = args[0]
+		case "hier":
+			ctx.InhibKernel = KernelHierarchical
+		default:
+			fmt.Println("usage: kernel [global|overlap|hierarchical]")
+			return
+		}
+		journalf(ctx, "KERNEL %s", ctx.InhibKernel)
+	}
+	fmt.Printf("Inhibition kernel = %s\n", ctx.InhibKernel)
+}
@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestIsComponentOf(t *testing.T) {
+	ctx := NewContext()
+	for _, s := range []string{"[1-2]", "[2-3]", "(1>2)", "[[1-2]-3]"} {
+		if err := InjectStructure(ctx, s); err != nil {
+			t.Fatalf("inject %s: %v", s, err)
+		}
+	}
+	ctx.AddBlock(NewComposeBlock("[[1-2]-3]", "4"))
+	tests := []struct {
+		k, c string
+		want bool
+	}{
+		{"[1-2]", "[[1-2]-3]", true},
+		{"[1-2]", "[[[1-2]-3]-4]", true},
+		{"[[1-2]-3]", "[[[1-2]-3]-4]", true},
+		{"[2-3]", "[[1-2]-3]", false}, // same tokens, different base
+		{"(1>2)", "[[1-2]-3]", false},
+		{"[[1-2]-3]", "[1-2]", false},
+		{"[1-2]", "[1-2]", false},
+		{"[1-2]", "[2-3]", false},
+	}
+	for _, tt := range tests {
+		if got := isComponentOf(ctx, tt.k, tt.c); got != tt.want {
+			t.Errorf("isComponentOf(%s, %s) = %v, want %v", tt.k, tt.c, got, tt.want)
+		}
+	}
+}
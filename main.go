@@ -38,6 +38,7 @@ import (
 	"bufio"
 	"fmt"
 	"math"
+	"math/rand"
 	"os"
 	"sort"
 	"strings"
@@ -102,6 +103,18 @@ func clearFloatMap(m map[string]float64) {
 	}
 }
 
+// sortedKeys returns the keys of m in ascending order.
+// Loops whose effects depend on visiting order (sums, queue order, tie-breaks)
+// iterate over sortedKeys instead of ranging over the map directly.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // Kind is the signal type. It defines how a Signal should be interpreted by blocks and the field.
 type Kind string
 
@@ -122,7 +135,7 @@ const (
 	K_INHIB Kind = "INHIB" // inhibition marker (used for suppression/competition)
 
 	// Output
-	K_DRIVE  Kind = "DRIVE"  // reserved: action drive (future use)
+	K_DRIVE  Kind = "DRIVE"  // drive / reward signal (REWARD and PUNISH modulate plasticity)
 	K_ACTION Kind = "ACTION" // emitted action (observable output)
 )
 
@@ -151,6 +164,10 @@ type Block interface {
 	// Tick allows time-based updates (decay, accumulation, cooldowns).
 	// It may also emit signals.
 	Tick(ctx *Context) []Signal
+
+	// Clone returns an independent copy of the block, internal state
+	// included, for a tentative run on a copy of the context.
+	Clone() Block
 }
 
 type Context struct {
@@ -177,6 +194,82 @@ type Context struct {
 	PrevSens string
 	LastSens string
 
+	// Multi-channel input ("audio:beep", "light:red"). Tokens without a
+	// "chan:" prefix belong to the default channel "".
+	SensNow    []string          // all sensory tokens of the current tick
+	PrevByChan map[string]string // previous token per channel
+	LastByChan map[string]string // latest token per channel
+	ChanTick   map[string]int    // tick of the latest input per channel
+	ChanErrs   map[string]int    // prediction errors per channel
+
+	SeqPairsNow   [][2]string // adjacent (prev, latest) token pairs this tick
+	SimulPairsNow [][2]string // cross-channel token pairs sensed together this tick
+
+	// Continuous-valued sensors ("temp=21.5")
+	NumFields     map[string][]*RangeSensorBlock // receptive fields per variable, sorted by lower bound
+	NumLast       map[string]float64             // latest raw value per variable
+	NumInitWidth  float64                        // width of a newly created field
+	NumSplitAfter int                            // clustered errors needed to split a field
+	NumMinWidth   float64                        // fields are never split below this width
+
+	// Variable-gap sequences and rhythms
+	SensLog        []TimedTok         // tick-stamped sensory tokens, TemporalWindow ticks long
+	TemporalWindow int                // history kept for gap and rhythm learning
+	MaxGap         int                // longest learned gap "a then b after k ticks"; < 2 disables gap and rhythm learning
+	GapTol         int                // tolerance (ticks) for gaps and rhythm periods
+	SeenGaps       map[string]float64 // occurrences per "a>b@k"; negative = crystallized
+	GapBase        map[string]int     // occurrences of each token as the start of a gap
+	LastSeenAt     map[string]int     // last tick each token was sensed
+	LastInterval   map[string]int     // last recurrence interval per token
+	IntervalRun    map[string]int     // consecutive repeats of LastInterval
+	TimedExpect    []TimedExpect      // expectations armed for a future tick
+
+	ExpectHorizon int     // steps ahead the winner's expectation is chained (1 = next tick only)
+	HorizonDecay  float64 // per-step confidence decay along the chain
+
+	SilenceToken string // token sensed on idle ticks ("" = idle ticks carry no input)
+
+	// Delayed delivery: signals scheduled for a future tick
+	Scheduled SignalHeap
+	SchedSeq  int
+
+	// Propagation budget and diagnostics
+	MaxRounds     int      // safety bound on propagation rounds per tick
+	SignalBudget  int      // max signals processed per tick
+	MassBudget    float64  // max total signal mass processed per tick
+	PropDiag      PropDiag // diagnostics of the last tick
+	PropIncidents int      // ticks with cycles or truncated chains so far
+	PriorityQueue bool     // process each round strongest signal first (default: insertion order)
+
+	// Determinism audit
+	TrackDigest bool     // record a state digest after every tick
+	Digests     []string // per-tick digests while TrackDigest is on
+
+	// Seeded stochastic mode (off while Rand is nil)
+	Rand        *rand.Rand
+	Seed        int64
+	RandDraws   int     // draws taken from Rand since seeding
+	Temperature float64 // softmax temperature for winner selection
+	ExploreRate float64 // chance per tick of an exploratory action
+	SamplePreds bool    // arm expectations sampled from transition weights
+	NoiseDrop   float64 // per-token input noise rates
+	NoiseSwap   float64
+	NoiseSubst  float64
+
+	Journal []string // tick-stamped record of configuration changes and edits
+
+	// Memory budget (0 = unlimited); enforced every tick by utility-based eviction
+	MaxBlocks int
+	MaxBytes  int
+	Evictions int // blocks evicted so far
+
+	// Offline consolidation ("sleep")
+	EpisodeStore     [][]string // recent episodes kept for replay
+	EpisodeStoreMax  int
+	AutoSleep        bool    // sleep automatically when energy runs low
+	SleepBelowEnergy float64 // auto-sleep threshold
+	SleepTicks       int     // replay budget of one sleep phase
+
 	PrevStructSet map[string]bool 
 	ThisStructSet map[string]bool 
 
@@ -224,10 +317,46 @@ type Context struct {
 	
 	ErrCooldown      map[string]int 
 	ErrCooldownTicks int            
+	CooldownHits     int // mispredictions absorbed by a structure's error cooldown
+	ErrBoostTicks    int // ticks spent with the error boost (ErrTTL) active
 
 	
 	BlockLastFire map[string]int 
+	FireCount     map[string]int // emissions per block, for utility-based eviction
 	ForgetAfter   int            
+	ForgetPolicy   ForgetPolicy            // policy for all learned block types (nil = age rule)
+	ForgetPolicies map[string]ForgetPolicy // per block prefix overrides ("SEQ:" ...)
+	Pinned         map[string]bool         // structures kept regardless of forgetting, budget and sleep
+
+	// Decay of unobserved predictions
+	TransHalfLife int            // ticks for idle transition weights and PredConf to halve (0 = off)
+	DecayGrace    int            // idle ticks before decay starts
+	LastObserved  map[string]int // last tick each structure was active
+
+	// Context-gated prediction exceptions
+	Exceptions     map[string]*Exception // by "struct|context"
+	ExceptEvidence map[string]float64    // by "struct|context|token"
+	ExpectCtx      map[string]string     // context of each armed expectation
+	BaseHitCtx     map[string]string     // context of the last confirmed base prediction since the last error
+
+	// Learned negative links (NegLinkBlock)
+	NegLearn        bool               // learn links from unfollowed predictions (off by default)
+	NegEvidence     map[string]float64 // by "a>b": evidence that b does not follow a
+	PredIssued      map[string]bool    // tokens predicted by active structures last tick
+	PredIssuedAfter string             // token sensed when they were predicted
+	NegSuppressed   map[string]float64 // PRED values suppressed this tick
+	NegForgetAfter  int                // links idle this long are pruned (0 = never)
+	NegMax          int                // cap on negative links (0 = unlimited)
+
+	// Hierarchy-aware competition
+	InhibKernel   string            // global | overlap | hierarchical
+	ExplainedAway map[string]string // component -> composite that absorbed it this tick
+
+	// Per-block-type energy accounting and shedding (metabolism.go)
+	BlockCosts map[string]BlockCost  // by block type ("COACT", "SEQ", ...)
+	EnergyUse  map[string]*EnergyUse // consumption per block type since the last reset
+	ShedBelow  float64               // shed low-priority blocks below this share of EnergyMax (0 = off)
+	ShedCutoff int                   // priority at or below which blocks are shed this tick
 	PruneEvery    int            
 
 	DemoFocusPairsOnly bool
@@ -236,8 +365,18 @@ type Context struct {
 
 	ActionsThisTick int
 	MaxActionsPerTick int 
+	ActionGroups    map[string]string // action name -> mutually-exclusive group (optional)
+	ActionCosts     map[string]float64 // action name -> energy cost (default actionCost)
+	ActionLosers    []string          // actions that lost arbitration this tick, with reasons
 	LastArmedExpect map[string]string 
 	LastArmedConf   map[string]float64 
+
+	// Reward-modulated plasticity (K_DRIVE)
+	Elig        map[string]float64 // eligibility traces: ActionBlock IDs, "TRANS:st->tok" and "BIND:..." keys
+	EligDecay   float64            // per-tick trace retention (lambda)
+	RewardGain  float64            // learning rate applied to traces on REWARD/PUNISH
+	PunishInhib float64            // inhibition added per unit of punishment
+	EnvBind     map[string]float64 // value of playing a move for a learned action, per "state|action->move"
 }
 
 func NewContext() *Context {
@@ -257,6 +396,41 @@ func NewContext() *Context {
 
 		PrevSens:      "",
 		LastSens:      "",
+		PrevByChan:    make(map[string]string),
+		LastByChan:    make(map[string]string),
+		ChanTick:      make(map[string]int),
+		ChanErrs:      make(map[string]int),
+
+		NumFields:     make(map[string][]*RangeSensorBlock),
+		NumLast:       make(map[string]float64),
+		NumInitWidth:  10,
+		NumSplitAfter: 4,
+		NumMinWidth:   0.5,
+
+		TemporalWindow: 24,
+		GapTol:         1,
+		SeenGaps:       make(map[string]float64),
+		GapBase:        make(map[string]int),
+		LastSeenAt:     make(map[string]int),
+		LastInterval:   make(map[string]int),
+		IntervalRun:    make(map[string]int),
+
+		ExpectHorizon: 1,
+		HorizonDecay:  0.85,
+
+		SilenceToken: "_",
+
+		Temperature: 0.5,
+		ExploreRate: 0.1,
+
+		MaxRounds:    32,
+		SignalBudget: 4096,
+		MassBudget:   2048,
+
+		EpisodeStoreMax:  16,
+		AutoSleep:        true,
+		SleepBelowEnergy: 1.0,
+		SleepTicks:       48,
 		PrevStructSet: make(map[string]bool),
 		ThisStructSet: make(map[string]bool),
 
@@ -286,6 +460,24 @@ func NewContext() *Context {
 		ErrCooldownTicks: 2,
 
 		BlockLastFire: make(map[string]int),
+		FireCount:     make(map[string]int),
+		TransHalfLife: 400,
+		DecayGrace:    100,
+		LastObserved:  make(map[string]int),
+		Exceptions:     make(map[string]*Exception),
+		ExceptEvidence: make(map[string]float64),
+		ExpectCtx:      make(map[string]string),
+		BaseHitCtx:     make(map[string]string),
+		NegEvidence:    make(map[string]float64),
+		PredIssued:     make(map[string]bool),
+		NegSuppressed:  make(map[string]float64),
+		NegForgetAfter: 600,
+		NegMax:         32,
+		InhibKernel:    KernelGlobal,
+		ExplainedAway:  make(map[string]string),
+		BlockCosts:     defaultBlockCosts(),
+		EnergyUse:      make(map[string]*EnergyUse),
+		ShedBelow:      0.25,
 		ForgetAfter:   120,
 		PruneEvery:    20,
 
@@ -293,6 +485,8 @@ func NewContext() *Context {
 		LastCleanupCount: 0,
 
 		MaxActionsPerTick: 1,
+		ActionGroups:      make(map[string]string),
+		ActionCosts:       make(map[string]float64),
 
 		DemoFocusPairsOnly: true,
 		DisableSeq: false,
@@ -300,6 +494,12 @@ func NewContext() *Context {
 		
 		LastArmedExpect: make(map[string]string),
 		LastArmedConf:   make(map[string]float64),
+
+		Elig:        make(map[string]float64),
+		EligDecay:   0.7,
+		RewardGain:  0.25,
+		PunishInhib: 0.8,
+		EnvBind:     make(map[string]float64),
 	}
 
 	return ctx
@@ -388,6 +588,8 @@ type SensorBlock struct {
 
 func (b *SensorBlock) ID() string { return "SENSOR:" + b.token }
 
+func (b *SensorBlock) Clone() Block { c := *b; return &c }
+
 func (b *SensorBlock) React(s Signal, ctx *Context) []Signal {
 	if s.Kind == K_SENS && s.Value == b.token {
 		return []Signal{{
@@ -433,27 +635,17 @@ func NewCoActBlock(a, b string) *CoActBlock {
 
 func (b *CoActBlock) ID() string { return "COACT:" + b.name }
 
+func (b *CoActBlock) Clone() Block { c := *b; return &c }
+
 func (b *CoActBlock) React(s Signal, ctx *Context) []Signal {
-    if s.Kind != K_ACT {
-        return nil
-    }
-    
-    if ctx.PrevSens == "" || ctx.LastSens == "" {
-        return nil
-    }
-    
-    a := ctx.PrevSens
-    c := ctx.LastSens
-    if a == c {
+    if s.Kind != K_ACT || (s.Value != b.a && s.Value != b.b) {
         return nil
     }
 
-    
-    if !((a == b.a && c == b.b) || (a == b.b && c == b.a)) {
+    if !coActiveNow(ctx, b.a, b.b, s.Value) {
         return nil
     }
 
-    
     ctx.BlockLastFire[b.ID()] = ctx.Tick
 
     if b.mature {
@@ -524,6 +716,8 @@ func NewSeqBlock(a, b string) *SeqBlock {
 
 func (b *SeqBlock) ID() string { return "SEQ:" + b.name }
 
+func (b *SeqBlock) Clone() Block { c := *b; return &c }
+
 func (b *SeqBlock) React(s Signal, ctx *Context) []Signal {
 	if s.Kind != K_ACT || s.Value != b.b {
 		return nil
@@ -625,6 +819,8 @@ func NewComposeBlock(base, x string) *ComposeBlock {
 
 func (b *ComposeBlock) ID() string { return "COMPOSE:" + b.name }
 
+func (b *ComposeBlock) Clone() Block { c := *b; return &c }
+
 func (b *ComposeBlock) React(s Signal, ctx *Context) []Signal {
 	if s.Kind != K_STRUCT && s.Kind != K_ACT {
 		return nil
@@ -692,6 +888,7 @@ type ActionBlock struct {
 	accum        float64
 	threshold    float64
 	decayPerTick float64
+	gain         float64 // link strength, shaped by reward (K_DRIVE)
 }
 
 func NewActionBlock(targetStruct, actionName string) *ActionBlock {
@@ -701,14 +898,17 @@ func NewActionBlock(targetStruct, actionName string) *ActionBlock {
 		accum:        0,
 		threshold:    2.0,
 		decayPerTick: 0.20,
+		gain:         1.0,
 	}
 }
 
 func (b *ActionBlock) ID() string { return "ACTIONBLOCK:" + b.actionName + "<-" + b.targetStruct }
 
+func (b *ActionBlock) Clone() Block { c := *b; return &c }
+
 func (b *ActionBlock) React(s Signal, ctx *Context) []Signal {
 	if s.Kind == K_STRUCT && s.Value == b.targetStruct {
-		b.accum += s.Mass
+		b.accum += s.Mass * b.gain
 		if b.accum >= b.threshold {
 			b.accum = b.threshold * 0.5
 			return []Signal{{
@@ -747,37 +947,13 @@ func (ctx *Context) AllowActionThisTick() bool {
 
 // applyInhibition adjusts signal strength before it propagates further.
 
-// It implements three mechanisms:
-// 1)Action rate limiting and energy cost
+// It implements two mechanisms:
 // 2)Temporary error-based amplification
 // 3)Competitive inhibition between active structures
+// Action rate limiting and energy cost (formerly mechanism 1) happen after
+// propagation, in arbitrateActions.
 func applyInhibition(ctx *Context, s Signal) Signal {
 
-	// 1) Action gating + energy model 
-	// Only a limited number of actions can fire per tick.
-	// Each action consumes energy; low energy reduces its strength.
-	if s.Kind == K_ACTION {
-
-		if !ctx.AllowActionThisTick() {
-			s.Mass = 0
-			return s
-		}
-
-		key := fmt.Sprintf("%d|%d|%s|%s", ctx.Tick, s.Kind, s.Value, s.From)
-		if ctx.CostedThisTick != nil && !ctx.CostedThisTick[key] {
-			ctx.CostedThisTick[key] = true
-
-			const cost = 0.8
-			if ctx.Energy < cost {
-				// Not enough energy: dampen action strength.
-				s.Mass *= 0.3
-			} else {
-				ctx.Energy -= cost
-				ctx.EnergySpentEpisode += cost
-			}
-		}
-	}
-
 	//  2) Error boost 
 	// Shortly after a prediction error, amplify STRUCT and PRED signals
 	// to accelerate adaptation.
@@ -787,7 +963,8 @@ func applyInhibition(ctx *Context, s Signal) Signal {
 
 	// 3) Competitive inhibition 
 	// Only activation-bearing signals participate in inhibition.
-	if s.Kind != K_ACT && s.Kind != K_STRUCT {
+	// Actions are included so that punishment (K_DRIVE) can suppress them.
+	if s.Kind != K_ACT && s.Kind != K_STRUCT && s.Kind != K_ACTION {
 		return s
 	}
 
@@ -810,8 +987,8 @@ func decayInhibition(ctx *Context) {
 	if f < 0.0 {
 		f = 0.0
 	}
-	for k, v := range ctx.Inhib {
-		nv := v * f
+	for _, k := range sortedKeys(ctx.Inhib) {
+		nv := ctx.Inhib[k] * f
 		if nv < 0.02 {
 			delete(ctx.Inhib, k)
 		} else {
@@ -835,7 +1012,8 @@ func decayErrCooldown(ctx *Context) {
 
 func argmaxMap(m map[string]float64) (bestKey string, bestVal float64, ok bool) {
 	first := true
-	for k, v := range m {
+	for _, k := range sortedKeys(m) {
+		v := m[k]
 		if first ||
 			v > bestVal ||
 			(math.Abs(v-bestVal) < 1e-9 && preferStructName(k, bestKey)) {
@@ -872,98 +1050,27 @@ func preferStructName(a, b string) bool {
 // -avoid unbounded accumulation of structures
 // -keep only structures that are recently useful (active or predictive)
 // -remove attached actions if their source structure is removed
+//
+// Which blocks are stale is decided by a ForgetPolicy per block type
+// (see forget.go); the default is the age rule driven by ForgetAfter.
 func pruneOldBlocks(ctx *Context) {
-	if ctx.ForgetAfter <= 0 {
-		return
-	}
-
 	kill := make(map[string]bool)
 
-	// ActionBlock IDs are encoded as: "ACTIONBLOCK:<actionName><-<targetStruct>"
-	// This helper extracts the target structure name.
-	getActionTarget := func(id string) string {
-		parts := strings.Split(id, "<-")
-		if len(parts) != 2 {
-			return ""
-		}
-		return parts[1]
-	}
-
-	// A structure is protected from pruning if it is:
-	// -predictive with decent confidence, or
-	// -has meaningful transition weights, or
-	// -was active very recently (this/previous tick window)
-	shouldProtectStruct := func(structName string) bool {
-		if ctx.BestPred[structName] != "" && ctx.PredConf[structName] >= 0.30 {
-			return true
-		}
-
-		if m, ok := ctx.TransCounts[structName]; ok && len(m) > 0 {
-			for _, w := range m {
-				if w >= 0.20 {
-					return true
-				}
-			}
-		}
-
-		if ctx.ThisStructSet[structName] || ctx.PrevStructSet[structName] {
-			return true
-		}
-		return false
-	}
-
-	// Extract structure name from learned block IDs.
-	getStructFromID := func(id string) string {
-		if strings.HasPrefix(id, "COACT:") {
-			return strings.TrimPrefix(id, "COACT:")
-		}
-		if strings.HasPrefix(id, "SEQ:") {
-			return strings.TrimPrefix(id, "SEQ:")
-		}
-		if strings.HasPrefix(id, "COMPOSE:") {
-			return strings.TrimPrefix(id, "COMPOSE:")
-		}
-		return ""
-	}
-
-	// Step 1: mark stale learned structure blocks for deletion.
-	// BlockLastFire is updated when a block emits or is involved in activation.
-	for id, last := range ctx.BlockLastFire {
-		age := ctx.Tick - last
-		if age < ctx.ForgetAfter {
-			continue
-		}
-
-		// Only prune learned structure producers (not sensors, not core logic).
-		if strings.HasPrefix(id, "COACT:") ||
-			strings.HasPrefix(id, "SEQ:") ||
-			strings.HasPrefix(id, "COMPOSE:") {
-
-			st := getStructFromID(id)
-			if st == "" {
-				continue
-			}
-
-			// Keep structures that are still useful.
-			if shouldProtectStruct(st) {
-				continue
-			}
-
+	// Step 1: let each block type's policy mark stale learned blocks.
+	for _, prefix := range learnedPrefixes {
+		for _, id := range policyFor(ctx, prefix).Select(ctx, forgetCandidates(ctx, prefix)) {
 			kill[id] = true
 		}
 	}
 
 	// Step 2: if a structure producer is removed, remove its attached actions too.
 	if len(kill) > 0 {
-		for id := range ctx.Blocks {
-			if !strings.HasPrefix(id, "ACTIONBLOCK:") {
+		for _, id := range sortedKeys(ctx.Blocks) {
+			ab, ok := ctx.Blocks[id].(*ActionBlock)
+			if !ok {
 				continue
 			}
-			target := getActionTarget(id)
-			if target == "" {
-				continue
-			}
-			if kill["COACT:"+target] || kill["SEQ:"+target] || kill["COMPOSE:"+target] {
+			if kill["COACT:"+ab.targetStruct] || kill["SEQ:"+ab.targetStruct] || kill["COMPOSE:"+ab.targetStruct] {
 				kill[id] = true
 			}
 		}
@@ -986,7 +1093,12 @@ func pruneOldBlocks(ctx *Context) {
 			last := ctx.BlockLastFire[id]
 			cands = append(cands, cand{id: id, age: ctx.Tick - last})
 		}
-		sort.Slice(cands, func(i, j int) bool { return cands[i].age > cands[j].age })
+		sort.Slice(cands, func(i, j int) bool {
+			if cands[i].age != cands[j].age {
+				return cands[i].age > cands[j].age
+			}
+			return cands[i].id < cands[j].id
+		})
 
 		trimmed := make(map[string]bool, maxDeletesPerCycle)
 		for i := 0; i < len(cands) && i < maxDeletesPerCycle; i++ {
@@ -999,6 +1111,7 @@ func pruneOldBlocks(ctx *Context) {
 	for id := range kill {
 		delete(ctx.Blocks, id)
 		delete(ctx.BlockLastFire, id)
+		delete(ctx.FireCount, id)
 	}
 	ctx.LastCleanupTick = ctx.Tick
 	ctx.LastCleanupCount = len(kill)
@@ -1045,6 +1158,52 @@ func RunTick(ctx *Context, incoming []Signal) []Signal {
 		ctx.PredConf = make(map[string]float64)
 	}
 
+	if ctx.FireCount == nil {
+		ctx.FireCount = make(map[string]int)
+	}
+	if ctx.LastObserved == nil {
+		ctx.LastObserved = make(map[string]int)
+	}
+	if ctx.Exceptions == nil {
+		ctx.Exceptions = make(map[string]*Exception)
+	}
+	if ctx.ExceptEvidence == nil {
+		ctx.ExceptEvidence = make(map[string]float64)
+	}
+	if ctx.ExpectCtx == nil {
+		ctx.ExpectCtx = make(map[string]string)
+	}
+	if ctx.BaseHitCtx == nil {
+		ctx.BaseHitCtx = make(map[string]string)
+	}
+	if ctx.NegEvidence == nil {
+		ctx.NegEvidence = make(map[string]float64)
+	}
+	if ctx.PredIssued == nil {
+		ctx.PredIssued = make(map[string]bool)
+	}
+	if ctx.NegSuppressed == nil {
+		ctx.NegSuppressed = make(map[string]float64)
+	}
+	if ctx.ExplainedAway == nil {
+		ctx.ExplainedAway = make(map[string]string)
+	}
+	if ctx.BlockCosts == nil {
+		ctx.BlockCosts = defaultBlockCosts()
+	}
+	if ctx.EnergyUse == nil {
+		ctx.EnergyUse = make(map[string]*EnergyUse)
+	}
+	if ctx.MaxRounds <= 0 {
+		ctx.MaxRounds = 32
+	}
+	if ctx.SignalBudget <= 0 {
+		ctx.SignalBudget = 4096
+	}
+	if ctx.MassBudget <= 0 {
+		ctx.MassBudget = 2048
+	}
+
 	if ctx.ThisStructSet == nil {
 		ctx.ThisStructSet = make(map[string]bool)
 	}
@@ -1069,6 +1228,45 @@ func RunTick(ctx *Context, incoming []Signal) []Signal {
 	if ctx.LastArmedConf == nil {
 		ctx.LastArmedConf = make(map[string]float64)
 	}
+	if ctx.Elig == nil {
+		ctx.Elig = make(map[string]float64)
+	}
+	if ctx.EnvBind == nil {
+		ctx.EnvBind = make(map[string]float64)
+	}
+	if ctx.PrevByChan == nil {
+		ctx.PrevByChan = make(map[string]string)
+	}
+	if ctx.LastByChan == nil {
+		ctx.LastByChan = make(map[string]string)
+	}
+	if ctx.ChanTick == nil {
+		ctx.ChanTick = make(map[string]int)
+	}
+	if ctx.ChanErrs == nil {
+		ctx.ChanErrs = make(map[string]int)
+	}
+	if ctx.NumFields == nil {
+		ctx.NumFields = make(map[string][]*RangeSensorBlock)
+	}
+	if ctx.NumLast == nil {
+		ctx.NumLast = make(map[string]float64)
+	}
+	if ctx.SeenGaps == nil {
+		ctx.SeenGaps = make(map[string]float64)
+	}
+	if ctx.GapBase == nil {
+		ctx.GapBase = make(map[string]int)
+	}
+	if ctx.LastSeenAt == nil {
+		ctx.LastSeenAt = make(map[string]int)
+	}
+	if ctx.LastInterval == nil {
+		ctx.LastInterval = make(map[string]int)
+	}
+	if ctx.IntervalRun == nil {
+		ctx.IntervalRun = make(map[string]int)
+	}
 
 	//       Carry over expectations from previous tick 
 
@@ -1109,18 +1307,32 @@ func RunTick(ctx *Context, incoming []Signal) []Signal {
 	if ctx.Energy > ctx.EnergyMax {
 		ctx.Energy = ctx.EnergyMax
 	}
+	updateShedCutoff(ctx)
 
 	//      Decay of inhibition and error cooldowns 
 
 	decayInhibition(ctx)
 	decayErrCooldown(ctx)
+	decayUnobserved(ctx)
 	if ctx.ErrTTL > 0 {
 		ctx.ErrTTL--
+		ctx.ErrBoostTicks++
 	}
 
+	//      Delivery of scheduled signals 
+	// Signals scheduled on earlier ticks join this tick's input; delivered
+	// drives modulate plasticity like external ones.
+
+	delivered := deliverDue(ctx)
+
+	//      Reward / punishment against eligibility traces 
+
+	applyDrive(ctx, append(append([]Signal{}, incoming...), delivered...))
+
 	clearBoolMap(ctx.ThisStructSet)
 	clearFloatMap(ctx.ThisStructMass)
 	clearStringMap(ctx.ThisExpect)
+	clearFloatMap(ctx.NegSuppressed)
 
 	errSignals := make([]Signal, 0, 4)
 	hadErrThisTick := false
@@ -1129,19 +1341,38 @@ func RunTick(ctx *Context, incoming []Signal) []Signal {
 	// Compare actual sensory input with armed expectations.
 	// Mismatch produces ERR and updates transition statistics.
 
-	actual := ""
+	// Expectations are checked per channel: a prediction for "audio:x" is only
+	// compared with what arrived on the audio channel this tick.
+	actualByChan := make(map[string][]string, 2)
 	for _, s := range incoming {
 		if s.Kind == K_SENS {
-			actual = s.Value
-			break
+			tok := sensToken(ctx, s.Value)
+			ch := chanOf(tok)
+			actualByChan[ch] = append(actualByChan[ch], tok)
 		}
 	}
 
-	if actual != "" {
-		for st, pred := range ctx.PendingExpect {
-			if pred == "" || pred == actual {
+	if len(actualByChan) > 0 {
+		for _, st := range sortedKeys(ctx.PendingExpect) {
+			pred := ctx.PendingExpect[st]
+			if pred == "" {
+				continue
+			}
+			ch := chanOf(pred)
+			got := actualByChan[ch]
+			if len(got) == 0 {
 				continue
 			}
+			if containsStr(got, pred) {
+				noteNumericResidual(ctx, pred)
+				exceptionHit(ctx, st, pred)
+				continue
+			}
+			actual := got[0]
+			ctx.ChanErrs[ch]++
+			noteNumericError(ctx, actual)
+			exceptionMiss(ctx, st, pred, actual)
+			negFromError(ctx, st, pred)
 
 			inCooldown := ctx.ErrCooldown[st] > 0
 
@@ -1176,7 +1407,9 @@ func RunTick(ctx *Context, incoming []Signal) []Signal {
 				}
 			}
 
-			if !inCooldown {
+			if inCooldown {
+				ctx.CooldownHits++
+			} else {
 				hadErrThisTick = true
 
 				ctx.ErrCooldown[st] = ctx.ErrCooldownTicks
@@ -1195,20 +1428,29 @@ func RunTick(ctx *Context, incoming []Signal) []Signal {
 		}
 	}
 
-	//     Update sensory history 
+	// Timed expectations (gaps, rhythms) are resolved every tick, with or without input.
+	errSignals = append(errSignals, checkTimedExpectations(ctx, actualByChan)...)
 
-	for _, s := range incoming {
-		if s.Kind == K_SENS {
-			ctx.PrevSens = ctx.LastSens
-			ctx.LastSens = s.Value
-		}
+	// Refine numeric receptive fields where errors cluster.
+	if ctx.LearningEnabled && ctx.LearnStruct {
+		splitReceptiveFields(ctx)
 	}
 
+	//     Update sensory history 
+
+	updateSensHistory(ctx, incoming)
+	appendSensLog(ctx)
+	learnNegative(ctx)
+
 	//     Tick-based internal dynamics
 
 	emitted := make([]Signal, 0, 128)
 	for _, id := range ctx.Order {
+		if !mayReact(ctx, id) {
+			continue
+		}
 		out := ctx.Blocks[id].Tick(ctx)
+		chargeReaction(ctx, id, len(out))
 		if len(out) > 0 {
 			emitted = append(emitted, out...)
 		}
@@ -1217,12 +1459,14 @@ func RunTick(ctx *Context, incoming []Signal) []Signal {
 	queue := append([]Signal{}, incoming...)
 	queue = append(queue, errSignals...)
 	queue = append(queue, emitted...)
+	queue = append(queue, delivered...)
 
 	//     Inject current model predictions into the field 
 
-	for st, tok := range ctx.BestPred {
+	for _, st := range sortedKeys(ctx.BestPred) {
+		tok := ctx.BestPred[st]
 		conf := ctx.PredConf[st]
-		if tok == "" || conf < 0.25 {
+		if tok == "" || conf < 0.25 || predInhibited(ctx, st, tok) {
 			continue
 		}
 		ps := Signal{
@@ -1237,72 +1481,39 @@ func RunTick(ctx *Context, incoming []Signal) []Signal {
 		}
 	}
 
-	//      Multi-round propagation 
-	// Signals can trigger further reactions within the same tick.
-
-	const rounds = 4
-	allOut := make([]Signal, 0, 256)
-
-	for r := 0; r < rounds && len(queue) > 0; r++ {
-		nextQueue := make([]Signal, 0, 256)
-
-		for _, raw := range queue {
-			s := applyInhibition(ctx, raw)
-			if s.Mass <= 0 {
-				continue
-			}
-
-			if s.Kind == K_STRUCT || s.Kind == K_ACTION || s.Kind == K_ACT {
-				if s.From != "" {
-					if _, ok := ctx.Blocks[s.From]; ok {
-						ctx.BlockLastFire[s.From] = ctx.Tick
-					}
-				}
-			}
-
-			if s.Kind == K_STRUCT {
-				ctx.RecentStruct = append(ctx.RecentStruct, s)
-
-				// Accumulate activation mass for competition.
-				ctx.ThisStructSet[s.Value] = true
-				ctx.ThisStructMass[s.Value] += s.Mass
-
-				// Emit prediction if not strongly suppressed.
-				if ctx.Inhib[s.Value] <= 0.7 {
-					if pred := ctx.BestPred[s.Value]; pred != "" {
-						nextQueue = append(nextQueue, Signal{
-							Kind:  K_PRED,
-							Value: fmt.Sprintf("%s->%s", s.Value, pred),
-							Mass:  0.6,
-							Time:  ctx.Tick,
-							From:  "FIELD:MODEL",
-						})
-					}
-				}
-			}
+	//      Propagation 
+	// Signals trigger further reactions within the same tick until the
+	// queue drains or a per-tick budget runs out (see propagate).
 
-			for _, id := range ctx.Order {
-				out := ctx.Blocks[id].React(s, ctx)
-				if len(out) > 0 {
-					nextQueue = append(nextQueue, out...)
-				}
-			}
+	allOut, actionCands := propagate(ctx, queue)
 
-			allOut = append(allOut, s)
-		}
+	//        Action arbitration
+	// Candidates compete by mass, inhibition and energy; losers are reported.
 
-		queue = nextQueue
-	}
+	actionCands = append(actionCands, exploreAction(ctx)...)
+	fired := arbitrateActions(ctx, actionCands)
+	deliverActions(ctx, fired)
+	allOut = append(allOut, fired...)
 
 	//        Competition result
 	// Select the strongest activated structure this tick.
 
+	// Under the hierarchical kernel composites first absorb their components
+	// and the most specific predicting structure is preferred.
+	explainAway(ctx)
+
 	winner := ""
 	wMass := 0.0
 
-	if len(ctx.ThisStructMass) > 0 {
+	if ctx.InhibKernel == KernelHierarchical {
+		winner, wMass = specificWinner(ctx)
+	}
+	if winner == "" && len(ctx.ThisStructMass) > 0 && ctx.Rand != nil {
+		winner, wMass = softmaxWinner(ctx)
+	} else if winner == "" && len(ctx.ThisStructMass) > 0 {
 		const eps = 1e-9
-		for st, mass := range ctx.ThisStructMass {
+		for _, st := range sortedKeys(ctx.ThisStructMass) {
+			mass := ctx.ThisStructMass[st]
 			if winner == "" ||
 				mass > wMass+eps ||
 				(mass >= wMass-eps && preferStructName(st, winner)) {
@@ -1311,19 +1522,9 @@ func RunTick(ctx *Context, incoming []Signal) []Signal {
 		}
 	}
 
-	// Inhibit competing structures to stabilize selection.
-	if winner != "" && len(ctx.ThisStructMass) > 1 {
-		for st, mass := range ctx.ThisStructMass {
-			if st == winner {
-				continue
-			}
-			add := 0.7
-			if wMass-mass > 0.5 {
-				add = 1.0
-			}
-			ctx.Inhib[st] += add
-		}
-	}
+	// Inhibit competing structures to stabilize selection; which ones is
+	// decided by the inhibition kernel (see hierarchy.go).
+	inhibitLosers(ctx, winner, wMass)
 
 	// Resource cost for selecting a winner.
 	if winner != "" {
@@ -1331,6 +1532,7 @@ func RunTick(ctx *Context, incoming []Signal) []Signal {
 		if ctx.Energy >= structWinnerCost {
 			ctx.Energy -= structWinnerCost
 			ctx.EnergySpentEpisode += structWinnerCost
+			noteSpend(ctx, "WINNER", structWinnerCost)
 		} else {
 			ctx.Inhib[winner] += 0.5
 		}
@@ -1341,14 +1543,28 @@ func RunTick(ctx *Context, incoming []Signal) []Signal {
 	}
 
 	clearStringMap(ctx.ThisExpect)
+	clearStringMap(ctx.ExpectCtx)
 
 	// Arm next-tick expectation only if this tick had no error.
+	// A learned exception for the current context overrides the base prediction.
 	if !hadErrThisTick {
-		if winner != "" && ctx.Inhib[winner] <= 0.7 {
-			if keepPred := ctx.BestPred[winner]; keepPred != "" {
-				ctx.ThisExpect[winner] = keepPred
+		if winner != "" {
+			if keepPred := samplePred(ctx, winner); keepPred != "" {
+				keepPred = applyException(ctx, winner, keepPred)
+				if predInhibited(ctx, winner, keepPred) {
+					// The structure or this transition is suppressed; arm nothing.
+				} else if negSuppressed(ctx, winner, keepPred) {
+					if ctx.PredEvents != nil {
+						ctx.PredEvents = append(ctx.PredEvents,
+							fmt.Sprintf("NEG-SUPPRESSED %s->%s (after %s)", winner, keepPred, ctx.LastSens))
+					}
+				} else {
+					ctx.ThisExpect[winner] = keepPred
+				}
 			}
 		}
+		armChannelExpectations(ctx, winner)
+		armHorizon(ctx, winner)
 	}
 
 	clearStringMap(ctx.PendingExpect)
@@ -1360,14 +1576,166 @@ func RunTick(ctx *Context, incoming []Signal) []Signal {
 	for k := range ctx.ThisStructSet {
 		ctx.PrevStructSet[k] = true
 	}
+	noteObserved(ctx)
 
 	if ctx.PruneEvery > 0 && ctx.Tick%ctx.PruneEvery == 0 {
 		pruneOldBlocks(ctx)
+		pruneNegLinks(ctx)
 	}
+	enforceBudget(ctx)
+
+	recordDigest(ctx)
 
 	return allOut
 }
 
+// gatedConf is the confidence of predicting targetTok from transition weights m.
+// Confidence is gated by:
+// -evidence amount (enough observations)
+// -margin over the second-best token (avoid premature certainty)
+func gatedConf(m map[string]float64, targetTok string) float64 {
+	const (
+		confirmN      = 4
+		evidenceStep  = 0.22
+		minMarginFrac = 0.35
+	)
+	eps := 1e-9
+
+	sumV := 0.0
+	for _, tok := range sortedKeys(m) {
+		sumV += m[tok]
+	}
+	targetV := m[targetTok]
+	if sumV <= eps || targetTok == "" || targetV <= 0 {
+		return 0.0
+	}
+
+	rawConf := targetV / sumV
+
+	minEvidenceForFull := float64(confirmN) * evidenceStep
+	eGate := 1.0
+	if minEvidenceForFull > eps {
+		eGate = targetV / minEvidenceForFull
+		if eGate > 1.0 {
+			eGate = 1.0
+		}
+		if eGate < 0.0 {
+			eGate = 0.0
+		}
+	}
+
+	secondV := 0.0
+	for tok, v := range m {
+		if tok == targetTok {
+			continue
+		}
+		if v > secondV {
+			secondV = v
+		}
+	}
+
+	mGate := 1.0
+	if secondV > 0 {
+		need := secondV * (1.0 + minMarginFrac)
+		if targetV < need {
+			mGate = targetV / (need + eps)
+			if mGate > 1.0 {
+				mGate = 1.0
+			}
+			if mGate < 0.0 {
+				mGate = 0.0
+			}
+		}
+	}
+
+	conf := rawConf * eGate * mGate
+
+	// Avoid "absolute certainty" appearance.
+	if eGate < 1.0 || mGate < 1.0 {
+		if conf > 0.95 {
+			conf = 0.95
+		}
+	} else {
+		if conf > 0.99 {
+			conf = 0.99
+		}
+	}
+
+	if conf < 0.01 {
+		return 0.0
+	}
+	return conf
+}
+
+// refreshPrediction re-derives BestPred and PredConf of st from its
+// transition weights after they were changed outside Plasticity.
+func refreshPrediction(ctx *Context, st string) {
+	m := ctx.TransCounts[st]
+	bestTok, bestV := "", 0.0
+	for _, tok := range sortedKeys(m) {
+		if m[tok] > bestV {
+			bestTok, bestV = tok, m[tok]
+		}
+	}
+	if bestTok == "" {
+		delete(ctx.BestPred, st)
+		delete(ctx.PredConf, st)
+		return
+	}
+	ctx.BestPred[st] = bestTok
+	ctx.PredConf[st] = gatedConf(m, bestTok)
+}
+
+// learnPairEvidence accumulates evidence for the unordered pair [a-b]
+// and crystallizes a CoActBlock once enough has been seen.
+func learnPairEvidence(ctx *Context, a, b string, step float64) {
+	k := pairKey(a, b)
+
+	// SeenPairs[k] accumulates evidence. A negative value means "already crystallized".
+	if v, ok := ctx.SeenPairs[k]; ok && v < 0 {
+		return
+	}
+	ctx.SeenPairs[k] += step
+	if ctx.SeenPairs[k] >= 1.0 {
+		name := canonicalPairName(a, b)
+		id := "COACT:" + name
+
+		// Crystallization point: enough evidence collected -> materialize a new block.
+		if _, exists := ctx.Blocks[id]; !exists {
+			ctx.AddBlock(NewCoActBlock(a, b))
+			ctx.TrainEvents = append(ctx.TrainEvents, fmt.Sprintf("+++ LEARNED NEW PAIR BLOCK %s", name))
+		}
+		ctx.SeenPairs[k] = -1.0
+	}
+}
+
+// learnSeqEvidence accumulates evidence for the ordered transition (a>b)
+// and crystallizes a SeqBlock with its demo action link.
+func learnSeqEvidence(ctx *Context, a, b string, step float64) {
+	sk := a + ">" + b
+
+	// SeenSeq[sk] accumulates evidence. A negative value means "already crystallized".
+	if v, ok := ctx.SeenSeq[sk]; ok && v < 0 {
+		return
+	}
+	ctx.SeenSeq[sk] += step
+	if ctx.SeenSeq[sk] >= 1.0 {
+		name := fmt.Sprintf("(%s>%s)", a, b)
+		id := "SEQ:" + name
+
+		// Crystallize a new SeqBlock and optionally attach a simple action link for the demo.
+		if _, exists := ctx.Blocks[id]; !exists {
+			ctx.AddBlock(NewSeqBlock(a, b))
+			ctx.TrainEvents = append(ctx.TrainEvents, fmt.Sprintf("+++ LEARNED NEW SEQ BLOCK %s", name))
+
+			actName := "ACT_ON_" + name
+			ctx.AddBlock(NewActionBlock(name, actName))
+			ctx.TrainEvents = append(ctx.TrainEvents, fmt.Sprintf("+++ ATTACHED ACTION %s <- %s", actName, name))
+		}
+		ctx.SeenSeq[sk] = -1.0
+	}
+}
+
 const PredSwitchMass = 1.0
 
 // Plasticity performs online learning.
@@ -1379,77 +1747,48 @@ func Plasticity(ctx *Context, hadErrThisTick bool) {
 
 	// 1) Learn unordered co-activation pairs: [a-b] 
 	// When two different sensory tokens repeat adjacent enough times, we "crystallize" a CoActBlock.
+	// Adjacency is checked per channel; tokens arriving together on different channels
+	// also count as co-activated.
 	if ctx.LearnStruct {
-		if ctx.PrevSens != "" && ctx.LastSens != "" && ctx.PrevSens != ctx.LastSens {
-			k := pairKey(ctx.PrevSens, ctx.LastSens)
-
-			// SeenPairs[k] accumulates evidence. A negative value means "already crystallized".
-			if v, ok := ctx.SeenPairs[k]; ok && v < 0 {
-
-			} else {
-				ctx.SeenPairs[k] += 0.40 * structBoost
-				if ctx.SeenPairs[k] >= 1.0 {
-					name := canonicalPairName(ctx.PrevSens, ctx.LastSens)
-					id := "COACT:" + name
-
-					// Crystallization point: enough evidence collected -> materialize a new block.
-					if _, exists := ctx.Blocks[id]; !exists {
-						ctx.AddBlock(NewCoActBlock(ctx.PrevSens, ctx.LastSens))
-						ctx.TrainEvents = append(ctx.TrainEvents, fmt.Sprintf("+++ LEARNED NEW PAIR BLOCK %s", name))
-					}
-
-					ctx.SeenPairs[k] = -1.0
-				}
-			}
+		for _, p := range sensPairsNow(ctx) {
+			learnPairEvidence(ctx, p[0], p[1], 0.40*structBoost)
+		}
+		for _, p := range simulPairsNow(ctx) {
+			learnPairEvidence(ctx, p[0], p[1], 0.40*structBoost)
 		}
 	}
 
 	// 2) Learn ordered transitions: (a>b) 
 	// Similar to pair learning, but preserves order. Can be disabled for demo clarity.
 	if ctx.LearnStruct && !ctx.DisableSeq {
-		if ctx.PrevSens != "" && ctx.LastSens != "" && ctx.PrevSens != ctx.LastSens {
-			sk := ctx.PrevSens + ">" + ctx.LastSens
-
-			// SeenSeq[sk] accumulates evidence. A negative value means "already crystallized".
-			if v, ok := ctx.SeenSeq[sk]; ok && v < 0 {
-
-			} else {
-				ctx.SeenSeq[sk] += 0.45 * structBoost
-				if ctx.SeenSeq[sk] >= 1.0 {
-					name := fmt.Sprintf("(%s>%s)", ctx.PrevSens, ctx.LastSens)
-					id := "SEQ:" + name
-
-					// Crystallize a new SeqBlock and optionally attach a simple action link for the demo.
-					if _, exists := ctx.Blocks[id]; !exists {
-						ctx.AddBlock(NewSeqBlock(ctx.PrevSens, ctx.LastSens))
-						ctx.TrainEvents = append(ctx.TrainEvents, fmt.Sprintf("+++ LEARNED NEW SEQ BLOCK %s", name))
-
-						actName := "ACT_ON_" + name
-						ctx.AddBlock(NewActionBlock(name, actName))
-						ctx.TrainEvents = append(ctx.TrainEvents, fmt.Sprintf("+++ ATTACHED ACTION %s <- %s", actName, name))
-					}
-
-					ctx.SeenSeq[sk] = -1.0
-				}
-			}
+		for _, p := range sensPairsNow(ctx) {
+			learnSeqEvidence(ctx, p[0], p[1], 0.45*structBoost)
 		}
 	}
 
+	// 2b) Learn variable-gap transitions (a>b@k) and rhythms ~a/k 
+	if ctx.LearnStruct && !ctx.DisableSeq {
+		learnTemporal(ctx)
+	}
+
 	//  3) Learn compositions: [base-x] 
 	// If a structure was active in the previous tick and a new token appears, form a higher-order ComposeBlock.
 	if ctx.LearnStruct {
-		if ctx.LastSens != "" && len(ctx.PrevStructSet) > 0 {
-			for base := range ctx.PrevStructSet {
+		for _, x := range ctx.SensNow {
+			if len(ctx.PrevStructSet) == 0 {
+				break
+			}
+			for _, base := range sortedKeys(ctx.PrevStructSet) {
 				a, b, ok := parsePairMembers(base)
 				if !ok {
 					continue
 				}
 				// avoid trivial compositions where x is already part of the base pair
-				if ctx.LastSens == a || ctx.LastSens == b {
+				if x == a || x == b {
 					continue
 				}
 
-				ck := base + "||" + ctx.LastSens
+				ck := base + "||" + x
 				if v, ok := ctx.SeenComposes[ck]; ok && v < 0 {
 					continue
 				}
@@ -1457,12 +1796,12 @@ func Plasticity(ctx *Context, hadErrThisTick bool) {
 				// SeenComposes[ck] accumulates evidence for [base-x] composition.
 				ctx.SeenComposes[ck] += 0.28 * structBoost
 				if ctx.SeenComposes[ck] >= 1.0 {
-					name := fmt.Sprintf("[%s-%s]", base, ctx.LastSens)
+					name := fmt.Sprintf("[%s-%s]", base, x)
 					id := "COMPOSE:" + name
 
 					// Crystallization point for a composed structure.
 					if _, exists := ctx.Blocks[id]; !exists {
-						ctx.AddBlock(NewComposeBlock(base, ctx.LastSens))
+						ctx.AddBlock(NewComposeBlock(base, x))
 						ctx.TrainEvents = append(ctx.TrainEvents, fmt.Sprintf("+++ LEARNED NEW COMPOSE BLOCK %s", name))
 
 						actName := "ACT_ON_" + name
@@ -1479,25 +1818,31 @@ func Plasticity(ctx *Context, hadErrThisTick bool) {
 	//     4) Learn predictions (per-structure local transition statistics) 
 	// For each structure that was active in the previous tick, update its token transition weights.
 	if ctx.LearnPred {
-		if ctx.LastSens != "" && len(ctx.PrevStructSet) > 0 {
-			for st := range ctx.PrevStructSet {
+		if len(ctx.SensNow) > 0 && len(ctx.PrevStructSet) > 0 {
+			for _, st := range sortedKeys(ctx.PrevStructSet) {
 				if _, ok := ctx.TransCounts[st]; !ok {
 					ctx.TransCounts[st] = make(map[string]float64)
 				}
 
-				// Update transition weight for: st -> LastSens.
+				// Update transition weight for: st -> each token sensed this tick.
 				// When recent error boost is active, use a smaller step to avoid unstable overshoot.
 				learnRate := 0.22
 				if ctx.ErrTTL > 0 {
 					learnRate = 0.12
 				}
-				ctx.TransCounts[st][ctx.LastSens] += learnRate
+				for _, tok := range ctx.SensNow {
+					ctx.TransCounts[st][tok] += learnRate
+					if ctx.Elig != nil {
+						ctx.Elig["TRANS:"+st+"->"+tok] = 1.0
+					}
+				}
 
 				// Choose current best token prediction for this structure.
 				bestTok := ""
 				bestV := -1.0
 				sumV := 0.0
-				for tok, v := range ctx.TransCounts[st] {
+				for _, tok := range sortedKeys(ctx.TransCounts[st]) {
+					v := ctx.TransCounts[st][tok]
 					sumV += v
 					if v > bestV {
 						bestV = v
@@ -1508,78 +1853,6 @@ func Plasticity(ctx *Context, hadErrThisTick bool) {
 				oldPred := ctx.BestPred[st]
 				oldConf := ctx.PredConf[st]
 
-				const (
-					confirmN      = 4
-					evidenceStep  = 0.22
-					minMarginFrac = 0.35
-				)
-				eps := 1e-9
-
-				// Confidence is gated by:
-				// -evidence amount (enough observations)
-				// -margin over the second-best token (avoid premature certainty)
-				computeGatedConf := func(targetTok string, targetV float64) float64 {
-					if sumV <= eps || targetTok == "" || targetV <= 0 {
-						return 0.0
-					}
-
-					rawConf := targetV / sumV
-
-					minEvidenceForFull := float64(confirmN) * evidenceStep
-					eGate := 1.0
-					if minEvidenceForFull > eps {
-						eGate = targetV / minEvidenceForFull
-						if eGate > 1.0 {
-							eGate = 1.0
-						}
-						if eGate < 0.0 {
-							eGate = 0.0
-						}
-					}
-
-					secondV := 0.0
-					for tok, v := range ctx.TransCounts[st] {
-						if tok == targetTok {
-							continue
-						}
-						if v > secondV {
-							secondV = v
-						}
-					}
-
-					mGate := 1.0
-					if secondV > 0 {
-						need := secondV * (1.0 + minMarginFrac)
-						if targetV < need {
-							mGate = targetV / (need + eps)
-							if mGate > 1.0 {
-								mGate = 1.0
-							}
-							if mGate < 0.0 {
-								mGate = 0.0
-							}
-						}
-					}
-
-					conf := rawConf * eGate * mGate
-
-					// Avoid "absolute certainty" appearance.
-					if eGate < 1.0 || mGate < 1.0 {
-						if conf > 0.95 {
-							conf = 0.95
-						}
-					} else {
-						if conf > 0.99 {
-							conf = 0.99
-						}
-					}
-
-					if conf < 0.01 {
-						return 0.0
-					}
-					return conf
-				}
-
 				if bestTok != "" && sumV > 0 {
 					oldV := 0.0
 					if oldPred != "" {
@@ -1616,14 +1889,12 @@ func Plasticity(ctx *Context, hadErrThisTick bool) {
 
 					if allowSwitch {
 						ctx.BestPred[st] = bestTok
-						ctx.PredConf[st] = computeGatedConf(bestTok, bestV)
+						ctx.PredConf[st] = gatedConf(ctx.TransCounts[st], bestTok)
 					} else {
 						// Keep the previous prediction but slightly decay confidence.
 						ctx.BestPred[st] = oldPred
 						if oldPred != "" {
-							v := ctx.TransCounts[st][oldPred]
-							c := computeGatedConf(oldPred, v)
-							ctx.PredConf[st] = c * 0.92
+							ctx.PredConf[st] = gatedConf(ctx.TransCounts[st], oldPred) * 0.92
 						} else {
 							ctx.PredConf[st] = oldConf * 0.92
 						}
@@ -1655,6 +1926,17 @@ func Plasticity(ctx *Context, hadErrThisTick bool) {
 func resetEpisodeBoundary(ctx *Context) {
 	
 	ctx.PrevSens, ctx.LastSens = "", ""
+	ctx.SensNow = ctx.SensNow[:0]
+	ctx.SeqPairsNow = ctx.SeqPairsNow[:0]
+	ctx.SimulPairsNow = ctx.SimulPairsNow[:0]
+	clearStringMap(ctx.PrevByChan)
+	clearStringMap(ctx.LastByChan)
+	clearIntMap(ctx.ChanTick)
+	ctx.SensLog = ctx.SensLog[:0]
+	ctx.TimedExpect = ctx.TimedExpect[:0]
+	clearIntMap(ctx.LastSeenAt)
+	clearIntMap(ctx.LastInterval)
+	clearIntMap(ctx.IntervalRun)
 
 	
 	clearBoolMap(ctx.PrevStructSet)
@@ -1663,6 +1945,9 @@ func resetEpisodeBoundary(ctx *Context) {
 		
 	clearStringMap(ctx.ThisExpect)
 	clearStringMap(ctx.PendingExpect)
+	clearStringMap(ctx.ExpectCtx)
+	clearBoolMap(ctx.PredIssued)
+	ctx.PredIssuedAfter = ""
 
 	
 	ctx.RecentActs = ctx.RecentActs[:0]
@@ -1676,6 +1961,7 @@ func resetEpisodeBoundary(ctx *Context) {
 	clearFloatMap(ctx.ThisStructMass)
 	clearFloatMap(ctx.Inhib)
 	clearIntMap(ctx.ErrCooldown)
+	clearFloatMap(ctx.Elig)
 
 }
 
@@ -1737,7 +2023,12 @@ func topInhibitions(ctx *Context, n int) []string {
 	for k, v := range ctx.Inhib {
 		arr = append(arr, kv{k: k, v: v})
 	}
-	sort.Slice(arr, func(i, j int) bool { return arr[i].v > arr[j].v })
+	sort.Slice(arr, func(i, j int) bool {
+		if arr[i].v != arr[j].v {
+			return arr[i].v > arr[j].v
+		}
+		return arr[i].k < arr[j].k
+	})
 	if len(arr) > n {
 		arr = arr[:n]
 	}
@@ -1822,11 +2113,10 @@ func suppressedFromErrs(ctx *Context, episodeErrs []string, limit int) []string
 	out := make([]string, 0, limit)
 
 	for _, ev := range episodeErrs {
-		parts := strings.SplitN(ev, ":", 2)
-		if len(parts) != 2 {
+		st, _, ok := splitErrStruct(ev)
+		if !ok {
 			continue
 		}
-		st := parts[0]
 		if st == "" || seen[st] {
 			continue
 		}
@@ -1864,6 +2154,9 @@ func printBoard(ctx *Context, episodeStructs, episodeActions, episodeErrs, episo
 
 	fmt.Printf("FIELD: energy=%.2f/%.2f\n", ctx.Energy, ctx.EnergyMax)
 	fmt.Printf("FIELD: energy_spent_episode=%.2f\n", ctx.EnergySpentEpisode)
+	if metabolismActive(ctx) {
+		fmt.Printf("FIELD: energy use (shed prio<=%d)=%v\n", ctx.ShedCutoff, energyTop(ctx, 5))
+	}
 
 	
 	if ctx.LastCleanupCount > 0 {
@@ -2002,6 +2295,50 @@ func printBoard(ctx *Context, episodeStructs, episodeActions, episodeErrs, episo
 		fmt.Printf("FIELD: inhib=%v\n", inhs)
 	}
 
+	if timed := timedExpectations(ctx, 6); len(timed) > 0 {
+		fmt.Printf("FIELD: timed expectations=%v\n", timed)
+	}
+
+	if ctx.MaxBlocks > 0 || ctx.MaxBytes > 0 {
+		fmt.Printf("FIELD: memory %s\n", memoryUsage(ctx))
+	}
+
+	if ctx.PropIncidents > 0 {
+		fmt.Printf("FIELD: propagation incidents=%d last=%s\n", ctx.PropIncidents, propDiagSummary(ctx.PropDiag, 4))
+	}
+
+	if ctx.InhibKernel != KernelGlobal && ctx.InhibKernel != "" {
+		fmt.Printf("FIELD: kernel=%s explained away=%v\n", ctx.InhibKernel, explainedSummary(ctx))
+	}
+
+	if neg := negLinkList(ctx, 6); len(neg) > 0 {
+		fmt.Printf("FIELD: negative links=%v\n", neg)
+	}
+
+	if exc := exceptionList(ctx, 4); len(exc) > 0 {
+		fmt.Printf("FIELD: exceptions=%v\n", exc)
+	}
+
+	if dec := decayingPredictions(ctx, 4); len(dec) > 0 {
+		fmt.Printf("FIELD: decaying (half-life=%d grace=%d)=%v\n", ctx.TransHalfLife, ctx.DecayGrace, dec)
+	}
+
+	if sched := scheduledSummary(ctx, 6); len(sched) > 0 {
+		fmt.Printf("FIELD: scheduled=%v\n", sched)
+	}
+
+	if nums := numericPredictions(ctx, 6); len(nums) > 0 {
+		fmt.Printf("FIELD: numeric predictions=%v\n", nums)
+	}
+
+	if chErrs := channelErrors(ctx); len(chErrs) > 0 {
+		fmt.Printf("FIELD: channel errors=%v\n", chErrs)
+	}
+
+	if gains := actionGains(ctx, 6); len(gains) > 0 {
+		fmt.Printf("FIELD: action gains=%v\n", gains)
+	}
+
 	
 	supp := suppressedFromErrs(ctx, ee, 6)
 	if len(supp) > 0 {
@@ -2031,20 +2368,39 @@ func printBoard(ctx *Context, episodeStructs, episodeActions, episodeErrs, episo
 
 func parseErrTriplet(ev string) (st, pred, actual string, ok bool) {
 	
-	i := strings.Index(ev, ":")
-	j := strings.LastIndex(ev, "->")
-	if i <= 0 || j <= i+1 || j+2 >= len(ev) {
+	st, rest, ok := splitErrStruct(ev)
+	j := strings.LastIndex(rest, "->")
+	if !ok || j <= 0 || j+2 >= len(rest) {
 		return "", "", "", false
 	}
-	st = ev[:i]
-	pred = ev[i+1 : j]
-	actual = ev[j+2:]
+	pred = rest[:j]
+	actual = rest[j+2:]
 	if st == "" || pred == "" || actual == "" {
 		return "", "", "", false
 	}
 	return st, pred, actual, true
 }
 
+// ensureSensor registers a SensorBlock for tok on first sight.
+// It reports whether a new sensor was created.
+func ensureSensor(ctx *Context, tok string) bool {
+	if name, v, ok := parseNumeric(tok); ok {
+		_, created := ensureNumField(ctx, name, v)
+		return created
+	}
+	if ctx.Sensors[tok] {
+		return false
+	}
+	ctx.AddBlock(&SensorBlock{token: tok})
+	ctx.Sensors[tok] = true
+	return true
+}
+
+// knownToken reports whether tok is sensed by a sensor or a live receptive field.
+func knownToken(ctx *Context, tok string) bool {
+	return ctx.Sensors[tok] || numFieldByToken(ctx, tok) != nil
+}
+
 type EpisodeReport struct {
 	Structs []string
 	Actions []string
@@ -2060,6 +2416,12 @@ func RunEpisodeLine(ctx *Context, line string, investorMode bool, demoRunning bo
 
 
 func RunEpisodeTokens(ctx *Context, tokens []string, investorMode bool, demoRunning bool, sleepMs int, autoBoard bool) EpisodeReport {
+	tokens, noise := noisyTokens(ctx, tokens)
+	if len(noise) > 0 {
+		cprintf(C_YELLOW, "NOISE: %v -> %v\n", noise, tokens)
+	}
+	recordEpisode(ctx, tokens)
+
 	episodeStructs := make([]string, 0, 32)
 	episodeActions := make([]string, 0, 32)
 	episodeErrs := make([]string, 0, 32)
@@ -2074,18 +2436,20 @@ func RunEpisodeTokens(ctx *Context, tokens []string, investorMode bool, demoRunn
 
 	for i, tok := range tokens {
 		
-		if !ctx.Sensors[tok] {
-			ctx.AddBlock(&SensorBlock{token: tok})
-			ctx.Sensors[tok] = true
-
-			if ctx.LearningEnabled {
-				fmt.Printf("+++ AUTO-SENSOR CREATED [%s]\n", tok)
-			} else {
-				fmt.Printf("+++ TOKEN REGISTERED [%s] (test mode; no learning)\n", tok)
+		// "a+b" delivers several tokens in the same tick (multi-channel input).
+		parts := splitSimul(tok)
+		inSigs := make([]Signal, 0, len(parts))
+		for _, p := range parts {
+			if ensureSensor(ctx, p) {
+				if ctx.LearningEnabled {
+					fmt.Printf("+++ AUTO-SENSOR CREATED [%s]\n", p)
+				} else {
+					fmt.Printf("+++ TOKEN REGISTERED [%s] (test mode; no learning)\n", p)
+				}
 			}
+			inSigs = append(inSigs, Signal{Kind: K_SENS, Value: p, Mass: 1.0, Time: ctx.Tick, From: "USER"})
 		}
-
-		inSig := Signal{Kind: K_SENS, Value: tok, Mass: 1.0, Time: ctx.Tick, From: "USER"}
+		single := len(parts) == 1
 
 		
 		oldConf := make(map[string]float64, len(ctx.PendingExpect))
@@ -2105,7 +2469,7 @@ func RunEpisodeTokens(ctx *Context, tokens []string, investorMode bool, demoRunn
 		pairK := ""
 		seqK := ""
 
-		if oldLast != "" && oldLast != tok {
+		if single && oldLast != "" && oldLast != tok {
 			pairK = pairKey(oldLast, tok)
 			oldPair = ctx.SeenPairs[pairK]
 			pairName = canonicalPairName(oldLast, tok)
@@ -2118,7 +2482,7 @@ func RunEpisodeTokens(ctx *Context, tokens []string, investorMode bool, demoRunn
 		
 		oldCompose := make(map[string]float64, 4)
 		composeName := make(map[string]string, 4)
-		if len(ctx.PrevStructSet) > 0 {
+		if single && len(ctx.PrevStructSet) > 0 {
 			for base := range ctx.PrevStructSet {
 				a, b, ok := parsePairMembers(base)
 				if !ok {
@@ -2135,7 +2499,7 @@ func RunEpisodeTokens(ctx *Context, tokens []string, investorMode bool, demoRunn
 
 		
 		oldTrans := make(map[string]float64, 4)
-		if ctx.ErrTTL == 0 && len(ctx.PrevStructSet) > 0 {
+		if single && ctx.ErrTTL == 0 && len(ctx.PrevStructSet) > 0 {
 			for st := range ctx.PrevStructSet {
 				if m, ok := ctx.TransCounts[st]; ok {
 					oldTrans[st] = m[tok]
@@ -2148,7 +2512,7 @@ func RunEpisodeTokens(ctx *Context, tokens []string, investorMode bool, demoRunn
 		ctx.PredEvents = ctx.PredEvents[:0]
 		ctx.TrainEvents = ctx.TrainEvents[:0]
 
-		out := RunTick(ctx, []Signal{inSig})
+		out := RunTick(ctx, inSigs)
 
 		
 		predEvents := append([]string(nil), ctx.PredEvents...)
@@ -2343,6 +2707,9 @@ func RunEpisodeTokens(ctx *Context, tokens []string, investorMode bool, demoRunn
 		if len(actions) > 0 && !(investorMode && demoRunning) {
 			cprintf(C_GREEN+C_BOLD, "           ACTION=%v\n", actions)
 		}
+		if len(ctx.ActionLosers) > 0 && !(investorMode && demoRunning) {
+			cprintf(C_GRAY, "           ACTION-LOST=%v\n", ctx.ActionLosers)
+		}
 		if len(errs) > 0 {
 			cprintf(C_RED+C_BOLD, "           ERROR=%v\n", errs)
 		}
@@ -2500,18 +2867,33 @@ func RunEpisodeTokens(ctx *Context, tokens []string, investorMode bool, demoRunn
 
 
 func main() {
-	ctx := NewContext()
-
-	
-	sleepMs := 12 
-	autoBoard := true
-	investorMode := false 
+	if len(os.Args) > 1 && os.Args[1] == "--check-determinism" {
+		path := ""
+		if len(os.Args) > 2 {
+			path = os.Args[2]
+		}
+		os.Exit(checkDeterminism(path))
+	}
 
-	
-	demoRunning := false
+	ctx := NewContext()
+	sess := newSession(ctx, true)
 
 	fmt.Println("STB DEMO (INHIB+PRED+ERROR+FORGET): signals -> blocks -> competition -> prediction -> error-driven learning -> forgetting.")
 	fmt.Println("Commands: train | test | reset | board | demo | quit")
+	fmt.Println("          generate [-sample] [-seed=N] <prefix...> <n>")
+	fmt.Println("          env <corridor|grid|bandit> [steps] [seed] | drive <reward|punish|name> [mass]")
+	fmt.Println("          actgroup <group> <action...> | actgroup clear | actcost <action> <cost>|default | horizon [n] [decay]")
+	fmt.Println("          gap [maxgap] [tol]   (learn \"a then b after k ticks\" and rhythms; maxgap 0 = off)")
+	fmt.Println("          clock <ms> <ticks> [items...]   (ms=0: simulated time, '.' = silent tick) | clock silence [<tok>|off]")
+	fmt.Println("          sleep [ticks] | sleep auto on|off")
+	fmt.Println("          gen osc|timer|drive|list|del ... | scenario <file>")
+	fmt.Println("          queue [fifo|mass] | digest [on|off]   (stb-demo --check-determinism [script])")
+	fmt.Println("          seed [N|off] | stoch temp=T explore=P sample=on|off drop=P swap=P subst=P | journal [n]")
+	fmt.Println("          robust [-model=...] [-levels=...] [-reps=N] [-seed=N] <tokens...>")
+	fmt.Println("          budget [blocks N] [bytes N] | forget [<type> <policy> [param]] | forget dry")
+	fmt.Println("          edit pin|unpin|delete|inject|weight|inhib|rename ... | decay [halflife] [grace] | except [clear]")
+	fmt.Println("          neg | neg learn on|off | neg forget <ticks> | neg max <n> | neg add <a> <b> | kernel [global|overlap|hierarchical]")
+	fmt.Println("          energy | energy cost <type|all> <react> [signal] | energy prio <type> <n> | energy shed <f> | energy reset")
 	fmt.Println("Suggested demo:")
 	fmt.Println("  demo   (runs 3 steps:")
 	fmt.Println("          1) crystallize pairs [1-2] and [2-3]")
@@ -2526,15 +2908,6 @@ func main() {
 	fmt.Println("Input tokens separated by spaces. Example: 1 2 1 2 1 2 3 1 2 3 1 2 4")
 
 	in := bufio.NewScanner(os.Stdin)
-
-	
-	lastEpisodeStructs := []string{}
-	lastEpisodeActions := []string{}
-	lastEpisodeErrs := []string{}
-
-	
-	lastBoardCtx := ctx
-
 	for {
 		fmt.Print("> ")
 		if !in.Scan() {
@@ -2544,240 +2917,8 @@ func main() {
 		if line == "" {
 			continue
 		}
-
-		switch strings.ToLower(line) {
-		case "quit", "exit":
+		if sess.handle(line) {
 			return
-
-		case "train":
-	    ctx.LearningEnabled = true
-	    ctx.LearnStruct = true
-	    ctx.LearnPred = true
-	    fmt.Println("MODE = TRAIN (learning enabled)")
-	    continue
-
-		case "test":
-	    ctx.LearningEnabled = false
-	    ctx.LearnStruct = false
-	    ctx.LearnPred = false
-	    fmt.Println("MODE = TEST (learning disabled)")
-	    continue
-
-	    case "color on":
-			colorLogs = true
-			fmt.Println("Color logs = ON")
-			continue
-
-		case "color off":
-			colorLogs = false
-			fmt.Println("Color logs = OFF")
-			continue
-
-		case "reset":
-			resetEpisodeBoundary(ctx)
-			
-			lastEpisodeStructs = nil
-			lastEpisodeActions = nil
-			lastEpisodeErrs = nil
-			fmt.Println("Reset episode boundary")
-			continue
-
-		case "board":
-			printBoard(lastBoardCtx, lastEpisodeStructs, lastEpisodeActions, lastEpisodeErrs, nil)
-			continue
-
-		case "autoboard on":
-			autoBoard = true
-			fmt.Println("Auto board = ON")
-			continue
-
-		case "autoboard off":
-			autoBoard = false
-			fmt.Println("Auto board = OFF")
-			continue
-
-		case "investor on":
-			investorMode = true
-			autoBoard = false
-			sleepMs = 0
-			fmt.Println("Investor mode = ON (concise event log, autoboard off, no sleep)")
-			continue
-
-		case "investor off":
-			investorMode = false
-			fmt.Println("Investor mode = OFF")
-			continue
-
-		case "predlog on":
-			showPredEvents = true
-			fmt.Println("Pred event log = ON")
-			continue
-
-		case "predlog off":
-			showPredEvents = false
-			fmt.Println("Pred event log = OFF")
-			continue
-
-		case "pairs on":
-		    ctx.DemoFocusPairsOnly = true
-		    fmt.Println("Pairs-only mode = ON (UI-only: hides non-[a-b] in episode/board output)")
-		    continue
-
-		case "pairs off":
-		    ctx.DemoFocusPairsOnly = false
-		    fmt.Println("Pairs-only mode = OFF (UI-only)")
-		    continue
-
-
-
-			
-case "demo":
-	
-	prevInvestorMode := investorMode
-	prevAutoBoard := autoBoard
-	prevSleepMs := sleepMs
-	prevDemoRunning := demoRunning
-
-	
-	demoRunning = true
-	investorMode = true
-	autoBoard = false
-	sleepMs = 0
-	fmt.Println("Demo: investor mode ON, running scripted sequence...")
-
-	
-	demoCtx := NewContext()
-	demoCtx.LearningEnabled = true
-	demoCtx.DemoFocusPairsOnly = true
-	demoCtx.DisableSeq = true
-
-	
-	lastEpisodeStructs = nil
-	lastEpisodeActions = nil
-	lastEpisodeErrs = nil
-
-	
-	demoCtx.SuppressPredLog = true
-	demoCtx.LearnStruct = true
-	demoCtx.LearnPred = false
-
-	fmt.Println("DEMO STEP 1/3: ACCUMULATION -> CRYSTALLIZATION")
-
-	
-	rep := RunEpisodeLine(
-		demoCtx,
-		"1 2 1 2 1 2 1 2 1 2 1 2   2 3 2 3 2 3 2 3 2 3 2 3",
-		investorMode, demoRunning, sleepMs, false,
-	)
-
-	lastEpisodeStructs, lastEpisodeActions, lastEpisodeErrs =
-		rep.Structs, rep.Actions, rep.Errs
-
-	printBoard(demoCtx, lastEpisodeStructs, lastEpisodeActions, lastEpisodeErrs, nil)
-	lastBoardCtx = demoCtx
-
-	fmt.Println("NOTE: Step 1 reports accumulation and block crystallization (new blocks). STRUCT signals appear in Step 2.")
-
-	
-	demoCtx.SuppressPredLog = false
-	demoCtx.LearnStruct = false
-	demoCtx.LearnPred = true
-	demoCtx.DemoFocusPairsOnly = true
-
-	fmt.Println("DEMO STEP 2/3: STRUCTURES -> PREDICTION")
-
-	
-	rep = RunEpisodeLine(
-		demoCtx,
-		"1 2 3 1 2 3 1 2 3 1 2 3 1 2 3 1 2 3",
-		investorMode, demoRunning, sleepMs, false,
-	)
-
-	lastEpisodeStructs, lastEpisodeActions, lastEpisodeErrs =
-		rep.Structs, rep.Actions, rep.Errs
-
-	printBoard(demoCtx, lastEpisodeStructs, lastEpisodeActions, lastEpisodeErrs, nil)
-	lastBoardCtx = demoCtx
-
-	
-	fmt.Println("DEMO STEP 3/3: MISPREDICTION -> INHIBITION + ERROR-BOOST -> FAST RE-LEARN")
-
-	
-	demoCtx.LearningEnabled = false
-	demoCtx.LearnStruct = false
-	demoCtx.LearnPred = false
-
-	rep = RunEpisodeLine(demoCtx, "1 2 3", investorMode, demoRunning, sleepMs, false)
-	lastEpisodeStructs, lastEpisodeActions, lastEpisodeErrs =
-		rep.Structs, rep.Actions, rep.Errs
-	demoPulse(demoCtx, "after prime episode: 1 2 3")
-
-	
-	demoCtx.LearningEnabled = true
-	demoCtx.LearnStruct = false
-	demoCtx.LearnPred = true
-
-	rep = RunEpisodeLine(
-		demoCtx,
-		"1 2 4 1 2 4 1 2 4 1 2 4 1 2 4 1 2 4",
-		investorMode, demoRunning, sleepMs, false,
-	)
-
-	lastEpisodeStructs, lastEpisodeActions, lastEpisodeErrs =
-		rep.Structs, rep.Actions, rep.Errs
-	demoPulse(demoCtx, "after clean switch episode: 1 2 4")
-	printBoard(demoCtx, lastEpisodeStructs, lastEpisodeActions, lastEpisodeErrs, nil)
-
-	
-	demoCtx.LearningEnabled = true
-	demoCtx.LearnStruct = false
-	demoCtx.LearnPred = true
-
-	rep = RunEpisodeLine(demoCtx, "1 2 4", investorMode, demoRunning, sleepMs, false)
-	lastEpisodeStructs, lastEpisodeActions, lastEpisodeErrs =
-		rep.Structs, rep.Actions, rep.Errs
-	demoPulse(demoCtx, "verify #1 (train): 1 2 4")
-
-	
-	demoCtx.LearningEnabled = false
-	demoCtx.LearnStruct = false
-	demoCtx.LearnPred = false
-
-	rep = RunEpisodeLine(demoCtx, "1 2 4", investorMode, demoRunning, sleepMs, false)
-	lastEpisodeStructs, lastEpisodeActions, lastEpisodeErrs =
-		rep.Structs, rep.Actions, rep.Errs
-
-	demoPulse(demoCtx, "verify #2 (test): 1 2 4")
-	printBoard(demoCtx, lastEpisodeStructs, lastEpisodeActions, lastEpisodeErrs, nil)
-	lastBoardCtx = demoCtx
-
-	
-	pairs := countBlocksByPrefix(demoCtx, "COACT:")
-	seqs := countBlocksByPrefix(demoCtx, "SEQ:")
-	comps := countBlocksByPrefix(demoCtx, "COMPOSE:")
-	acts := countBlocksByPrefix(demoCtx, "ACTIONBLOCK:")
-
-	fmt.Printf(
-		"DEMO SUMMARY: learned pairs=%d | seqs=%d | composes=%d | actionLinks=%d | blocks=%d\n",
-		pairs, seqs, comps, acts, len(demoCtx.Blocks),
-	)
-
-	demoCtx.DemoFocusPairsOnly = false
-
-	
-	investorMode = prevInvestorMode
-	autoBoard = prevAutoBoard
-	sleepMs = prevSleepMs
-	demoRunning = prevDemoRunning
-
-	continue
-
-
 		}
-
-		
-		rep := RunEpisodeLine(ctx, line, investorMode, demoRunning, sleepMs, autoBoard)
-		lastEpisodeStructs, lastEpisodeActions, lastEpisodeErrs = rep.Structs, rep.Actions, rep.Errs
-		lastBoardCtx = ctx
 	}
 }
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metabolism: every block reaction and every emitted signal can cost energy,
+// configured per block type. Consumption is accounted per type, including
+// the fixed winner and action charges. When energy falls below ShedBelow
+// (a fraction of EnergyMax) the lowest-priority block types stop reacting;
+// the lower the energy, the more types are shed. Sensors are never shed.
+
+// BlockCost configures one block type.
+type BlockCost struct {
+	React    float64 // per reaction (every React or Tick call, silent or not)
+	Signal   float64 // per emitted signal
+	Priority int     // higher survives longer; >= shedNever is never shed
+}
+
+// EnergyUse accumulates the consumption of one block type.
+type EnergyUse struct {
+	Reactions int
+	Signals   int
+	Shed      int // calls skipped by shedding or for lack of energy
+	Spent     float64
+}
+
+const shedNever = 9
+
+func defaultBlockCosts() map[string]BlockCost {
+	return map[string]BlockCost{
+		"SENSOR":      {Priority: 9},
+		"RANGE":       {Priority: 9},
+		"COACT":       {Priority: 7},
+		"SEQ":         {Priority: 7},
+		"NEG":         {Priority: 6},
+		"COMPOSE":     {Priority: 5},
+		"GAP":         {Priority: 4},
+		"RHYTHM":      {Priority: 4},
+		"ACTIONBLOCK": {Priority: 3},
+		"OSC":         {Priority: 2},
+		"TIMER":       {Priority: 2},
+		"DRIVEGEN":    {Priority: 2},
+	}
+}
+
+// blockType is the ID prefix of a block ("COACT:[1-2]" -> "COACT").
+func blockType(id string) string {
+	if i := strings.Index(id, ":"); i > 0 {
+		return id[:i]
+	}
+	return id
+}
+
+func costOf(ctx *Context, typ string) BlockCost {
+	if c, ok := ctx.BlockCosts[typ]; ok {
+		return c
+	}
+	return BlockCost{Priority: 5}
+}
+
+func useOf(ctx *Context, typ string) *EnergyUse {
+	u, ok := ctx.EnergyUse[typ]
+	if !ok {
+		u = &EnergyUse{}
+		ctx.EnergyUse[typ] = u
+	}
+	return u
+}
+
+// updateShedCutoff sets the priority at or below which blocks are shed this
+// tick: 0 while energy is above ShedBelow, rising to shedNever-1 as it runs out.
+func updateShedCutoff(ctx *Context) {
+	ctx.ShedCutoff = 0
+	if ctx.ShedBelow <= 0 || ctx.EnergyMax <= 0 {
+		return
+	}
+	f := ctx.Energy / ctx.EnergyMax
+	if f >= ctx.ShedBelow {
+		return
+	}
+	ctx.ShedCutoff = 2 + int((1-f/ctx.ShedBelow)*float64(shedNever-2))
+	if ctx.ShedCutoff > shedNever-1 {
+		ctx.ShedCutoff = shedNever - 1
+	}
+}
+
+// mayReact reports whether block id may react now; skipped calls are counted.
+func mayReact(ctx *Context, id string) bool {
+	typ := blockType(id)
+	c := costOf(ctx, typ)
+	if c.Priority >= shedNever {
+		return true
+	}
+	if c.Priority <= ctx.ShedCutoff || ctx.Energy < c.React {
+		useOf(ctx, typ).Shed++
+		return false
+	}
+	return true
+}
+
+// chargeReaction bills block id for a reaction that emitted n signals.
+// Silent reactions still pay the React cost.
+func chargeReaction(ctx *Context, id string, n int) {
+	typ := blockType(id)
+	c := costOf(ctx, typ)
+	u := useOf(ctx, typ)
+	u.Reactions++
+	u.Signals += n
+	spendEnergy(ctx, u, c.React+c.Signal*float64(n))
+}
+
+// noteSpend accounts a fixed charge (winner selection, actions) made elsewhere.
+func noteSpend(ctx *Context, typ string, cost float64) {
+	u := useOf(ctx, typ)
+	u.Reactions++
+	u.Spent += cost
+}
+
+func spendEnergy(ctx *Context, u *EnergyUse, cost float64) {
+	if cost <= 0 {
+		return
+	}
+	if cost > ctx.Energy {
+		cost = ctx.Energy
+	}
+	ctx.Energy -= cost
+	ctx.EnergySpentEpisode += cost
+	u.Spent += cost
+}
+
+// metabolismActive reports whether block costs or shedding are in effect.
+func metabolismActive(ctx *Context) bool {
+	if ctx.ShedCutoff > 0 {
+		return true
+	}
+	for _, c := range ctx.BlockCosts {
+		if c.React > 0 || c.Signal > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// energyTop renders the biggest consumers for the board.
+func energyTop(ctx *Context, n int) []string {
+	types := energyTypesBySpend(ctx)
+	if len(types) > n {
+		types = types[:n]
+	}
+	out := make([]string, 0, len(types))
+	for _, t := range types {
+		u := ctx.EnergyUse[t]
+		out = append(out, fmt.Sprintf("%s:%.2f(shed=%d)", t, u.Spent, u.Shed))
+	}
+	return out
+}
+
+func energyTypesBySpend(ctx *Context) []string {
+	types := sortedKeys(ctx.EnergyUse)
+	sort.SliceStable(types, func(i, j int) bool {
+		a, b := ctx.EnergyUse[types[i]], ctx.EnergyUse[types[j]]
+		if a.Spent != b.Spent {
+			return a.Spent > b.Spent
+		}
+		return a.Reactions > b.Reactions
+	})
+	return types
+}
+
+// printEnergyReport prints consumption per block type since the last reset.
+func printEnergyReport(ctx *Context) {
+	total := 0.0
+	for _, u := range ctx.EnergyUse {
+		total += u.Spent
+	}
+	cprintf(C_MAGENTA+C_BOLD, "ENERGY t=%03d energy=%.2f/%.2f regen=%.2f shed below %.0f%% (cutoff prio<=%d) spent=%.2f\n",
+		ctx.Tick, ctx.Energy, ctx.EnergyMax, ctx.EnergyRegen, ctx.ShedBelow*100, ctx.ShedCutoff, total)
+	fmt.Printf("  %-12s %4s %7s %7s %9s %8s %6s %8s %6s\n", "type", "prio", "react$", "signal$", "reactions", "signals", "shed", "spent", "share")
+	for _, t := range energyTypesBySpend(ctx) {
+		u := ctx.EnergyUse[t]
+		c := costOf(ctx, t)
+		share := 0.0
+		if total > 0 {
+			share = u.Spent / total * 100
+		}
+		fmt.Printf("  %-12s %4d %7.3f %7.3f %9d %8d %6d %8.2f %5.1f%%\n",
+			t, c.Priority, c.React, c.Signal, u.Reactions, u.Signals, u.Shed, u.Spent, share)
+	}
+}
+
+// cmdEnergy handles:
+//
+//	energy                                  consumption report
+//	energy cost <type|all> <react> [signal] per-reaction and per-signal cost
+//	energy prio <type> <n>                  shedding priority
+//	energy shed <fraction>                  shed below this share of EnergyMax (0 = off)
+//	energy reset                            clear the counters
+func cmdEnergy(ctx *Context, args []string) {
+	usage := func() {
+		fmt.Println("usage: energy | energy cost <type|all> <react> [signal] | energy prio <type> <n> | energy shed <fraction> | energy reset")
+	}
+	num := func(s string) (float64, bool) {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 {
+			fmt.Printf("energy: bad value %q\n", s)
+			return 0, false
+		}
+		return v, true
+	}
+
+	switch {
+	case len(args) == 0:
+	case args[0] == "reset" && len(args) == 1:
+		clear(ctx.EnergyUse)
+	case args[0] == "shed" && len(args) == 2:
+		v, ok := num(args[1])
+		if !ok {
+			return
+		}
+		ctx.ShedBelow = v
+		updateShedCutoff(ctx)
+		journalf(ctx, "ENERGY shed below %.2f", v)
+	case args[0] == "prio" && len(args) == 3:
+		v, err := strconv.Atoi(args[2])
+		if err != nil {
+			fmt.Printf("energy: bad priority %q\n", args[2])
+			return
+		}
+		typ := strings.ToUpper(args[1])
+		c := costOf(ctx, typ)
+		c.Priority = v
+		ctx.BlockCosts[typ] = c
+		journalf(ctx, "ENERGY prio %s=%d", typ, v)
+	case args[0] == "cost" && (len(args) == 3 || len(args) == 4):
+		react, ok := num(args[2])
+		if !ok {
+			return
+		}
+		sig := 0.0
+		if len(args) == 4 {
+			if sig, ok = num(args[3]); !ok {
+				return
+			}
+		}
+		types := []string{strings.ToUpper(args[1])}
+		if args[1] == "all" {
+			types = sortedKeys(ctx.BlockCosts)
+		}
+		for _, typ := range types {
+			c := costOf(ctx, typ)
+			c.React, c.Signal = react, sig
+			ctx.BlockCosts[typ] = c
+		}
+		journalf(ctx, "ENERGY cost %s react=%.3f signal=%.3f", args[1], react, sig)
+	default:
+		usage()
+		return
+	}
+	printEnergyReport(ctx)
+}
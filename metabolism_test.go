@@ -0,0 +1,61 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestChargeReaction(t *testing.T) {
+	tests := []struct {
+		name      string
+		cost      BlockCost
+		emitted   int
+		wantSpent float64
+	}{
+		{"free", BlockCost{Priority: 7}, 3, 0},
+		{"silent reaction", BlockCost{React: 0.1, Signal: 0.5, Priority: 7}, 0, 0.1},
+		{"one signal", BlockCost{React: 0.1, Signal: 0.5, Priority: 7}, 1, 0.6},
+		{"three signals", BlockCost{React: 0.1, Signal: 0.5, Priority: 7}, 3, 1.6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			ctx.Energy = 10
+			ctx.BlockCosts["COACT"] = tt.cost
+
+			chargeReaction(ctx, "COACT:[1-2]", tt.emitted)
+
+			u := ctx.EnergyUse["COACT"]
+			if u == nil || u.Reactions != 1 || u.Signals != tt.emitted {
+				t.Fatalf("use = %+v, want 1 reaction and %d signals", u, tt.emitted)
+			}
+			if math.Abs(u.Spent-tt.wantSpent) > 1e-9 || math.Abs(10-ctx.Energy-tt.wantSpent) > 1e-9 {
+				t.Errorf("spent %.2f (energy %.2f), want %.2f", u.Spent, ctx.Energy, tt.wantSpent)
+			}
+		})
+	}
+}
+
+func TestMayReactSheds(t *testing.T) {
+	tests := []struct {
+		name   string
+		energy float64
+		id     string
+		want   bool
+	}{
+		{"full energy", 10, "OSC:x", true},
+		{"low priority shed", 4, "OSC:x", false},
+		{"high priority kept", 4, "COACT:[1-2]", true},
+		{"sensor never shed", 0, "SENSOR:1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			ctx.EnergyMax, ctx.Energy, ctx.ShedBelow = 10, tt.energy, 0.5
+			updateShedCutoff(ctx)
+			if got := mayReact(ctx, tt.id); got != tt.want {
+				t.Errorf("mayReact(%s) = %v at cutoff %d, want %v", tt.id, got, ctx.ShedCutoff, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NegLinkBlock is an inhibitory link "after a, b does not follow", added with
+// "neg add" or, with NegLearn on, learned from predictions that keep failing.
+// While a is sensed it answers every PRED for b with a K_INHIB carrying the
+// PRED's value; propagate drops the suppressed prediction and it is not
+// armed as an expectation. Unlike ctx.Inhib the link does not decay: it is
+// weakened only when b does follow a, and pruned by pruneNegLinks.
+type NegLinkBlock struct {
+	a, b     string
+	name     string
+	strength float64 // 1.0 when learned; refutations lower it
+}
+
+func NewNegLinkBlock(a, b string) *NegLinkBlock {
+	return &NegLinkBlock{a: a, b: b, name: fmt.Sprintf("(%s!>%s)", a, b), strength: 1.0}
+}
+
+func (b *NegLinkBlock) ID() string { return "NEG:" + b.name }
+
+func (b *NegLinkBlock) Clone() Block { c := *b; return &c }
+
+func negID(a, b string) string { return fmt.Sprintf("NEG:(%s!>%s)", a, b) }
+
+func (b *NegLinkBlock) React(s Signal, ctx *Context) []Signal {
+	if s.Kind != K_PRED || !strings.HasSuffix(s.Value, "->"+b.b) || !containsStr(ctx.SensNow, b.a) {
+		return nil
+	}
+	return []Signal{{
+		Kind:  K_INHIB,
+		Value: s.Value,
+		Mass:  b.strength,
+		Time:  ctx.Tick,
+		From:  b.ID(),
+	}}
+}
+
+func (b *NegLinkBlock) Tick(ctx *Context) []Signal { return nil }
+
+const (
+	negStepMiss   = 0.15 // evidence per issued prediction that did not follow
+	negStepErr    = 0.50 // evidence per error against a confident prediction
+	negMinErrConf = 0.60 // confidence an erring prediction needs to count as negative evidence
+	negRefute     = 0.34 // strength lost each time b does follow a
+	negMinStr     = 0.20 // links weaker than this are removed
+)
+
+// predTok returns the predicted token of a PRED value "st->tok".
+func predTok(v string) string {
+	if i := strings.LastIndex(v, "->"); i >= 0 {
+		return v[i+2:]
+	}
+	return ""
+}
+
+// notePredIssued remembers the tokens predicted by structures active this tick,
+// so the next input can confirm or refute them.
+func notePredIssued(ctx *Context, s Signal) {
+	if s.From != "FIELD:MODEL" || len(ctx.SensNow) == 0 {
+		return
+	}
+	ctx.PredIssued[predTok(s.Value)] = true
+	ctx.PredIssuedAfter = ctx.SensNow[len(ctx.SensNow)-1]
+}
+
+// noteNegSuppression records a K_INHIB from a negative link and removes the
+// suppressed PRED from the tick's output.
+func noteNegSuppression(ctx *Context, s Signal, allOut []Signal) []Signal {
+	ctx.NegSuppressed[s.Value] = s.Mass
+	out := allOut[:0]
+	for _, o := range allOut {
+		if o.Kind != K_PRED || o.Value != s.Value {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// negSuppressed reports whether st's prediction tok was suppressed this tick.
+func negSuppressed(ctx *Context, st, tok string) bool {
+	return ctx.NegSuppressed[st+"->"+tok] >= 0.5
+}
+
+// learnNegative compares the predictions issued last tick with this tick's
+// input: predictions that keep not following their token accumulate negative
+// evidence (only with NegLearn on), and ones that do follow refute existing links.
+func learnNegative(ctx *Context) {
+	issued, a := ctx.PredIssued, ctx.PredIssuedAfter
+	if len(ctx.SensNow) == 0 || a == "" {
+		return
+	}
+	defer func() {
+		clearBoolMap(ctx.PredIssued)
+		ctx.PredIssuedAfter = ""
+	}()
+
+	if !ctx.LearningEnabled {
+		return
+	}
+	for _, tok := range ctx.SensNow {
+		refuteNegLink(ctx, a, tok)
+	}
+	if !ctx.NegLearn || !ctx.LearnStruct {
+		return
+	}
+	for _, tok := range sortedKeys(issued) {
+		if tok == a || containsStr(ctx.SensNow, tok) {
+			continue
+		}
+		addNegEvidence(ctx, a, tok, negStepMiss)
+	}
+}
+
+// negFromError adds evidence from an error against a confident prediction.
+func negFromError(ctx *Context, st, pred string) {
+	if !ctx.NegLearn || !ctx.LearningEnabled || !ctx.LearnStruct || ctx.LastSens == "" || ctx.PredConf[st] < negMinErrConf {
+		return
+	}
+	addNegEvidence(ctx, ctx.LastSens, pred, negStepErr)
+}
+
+func addNegEvidence(ctx *Context, a, b string, step float64) {
+	k := a + ">" + b
+	if _, ok := ctx.Blocks[negID(a, b)]; ok {
+		return
+	}
+	ctx.NegEvidence[k] += step
+	if ctx.NegEvidence[k] < 1.0 {
+		return
+	}
+	delete(ctx.NegEvidence, k)
+	nb := NewNegLinkBlock(a, b)
+	ctx.AddBlock(nb)
+	ctx.BlockLastFire[nb.ID()] = ctx.Tick
+	ctx.TrainEvents = append(ctx.TrainEvents, fmt.Sprintf("+++ LEARNED NEGATIVE LINK %s", nb.name))
+}
+
+// refuteNegLink weakens the link "a !> b" after b followed a, and clears
+// any evidence towards it. Callers only refute while learning is enabled.
+func refuteNegLink(ctx *Context, a, b string) {
+	delete(ctx.NegEvidence, a+">"+b)
+	nb, ok := ctx.Blocks[negID(a, b)].(*NegLinkBlock)
+	if !ok || ctx.Pinned[nb.name] {
+		return
+	}
+	nb.strength -= negRefute
+	if nb.strength < negMinStr {
+		removeBlock(ctx, nb.ID())
+		ctx.TrainEvents = append(ctx.TrainEvents, fmt.Sprintf("--- NEGATIVE LINK REFUTED %s", nb.name))
+	}
+}
+
+// pruneNegLinks applies the negative links' own rules: links whose tokens
+// are no longer known, or that have not suppressed anything for
+// NegForgetAfter ticks, are removed; beyond NegMax the weakest, least
+// recently used go first. Pinned links are kept.
+func pruneNegLinks(ctx *Context) {
+	links := make([]*NegLinkBlock, 0, 8)
+	for _, id := range sortedKeys(ctx.Blocks) {
+		nb, ok := ctx.Blocks[id].(*NegLinkBlock)
+		if !ok || ctx.Pinned[nb.name] {
+			continue
+		}
+		idle := ctx.Tick - ctx.BlockLastFire[id]
+		if !knownToken(ctx, nb.a) || !knownToken(ctx, nb.b) || (ctx.NegForgetAfter > 0 && idle >= ctx.NegForgetAfter) {
+			removeBlock(ctx, id)
+			ctx.TrainEvents = append(ctx.TrainEvents, fmt.Sprintf("--- PRUNED NEGATIVE LINK %s (idle %d)", nb.name, idle))
+			continue
+		}
+		links = append(links, nb)
+	}
+	if ctx.NegMax <= 0 || len(links) <= ctx.NegMax {
+		return
+	}
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].strength != links[j].strength {
+			return links[i].strength > links[j].strength
+		}
+		return ctx.BlockLastFire[links[i].ID()] > ctx.BlockLastFire[links[j].ID()]
+	})
+	for _, nb := range links[ctx.NegMax:] {
+		removeBlock(ctx, nb.ID())
+		ctx.TrainEvents = append(ctx.TrainEvents, fmt.Sprintf("--- PRUNED NEGATIVE LINK %s (over cap %d)", nb.name, ctx.NegMax))
+	}
+}
+
+// negLinkList renders the negative links for the board.
+func negLinkList(ctx *Context, n int) []string {
+	out := make([]string, 0, 4)
+	for _, id := range ctx.Order {
+		nb, ok := ctx.Blocks[id].(*NegLinkBlock)
+		if !ok {
+			continue
+		}
+		out = append(out, fmt.Sprintf("%s str=%.2f idle=%d", nb.name, nb.strength, ctx.Tick-ctx.BlockLastFire[id]))
+	}
+	if len(out) > n {
+		out = out[len(out)-n:]
+	}
+	return out
+}
+
+// cmdNeg handles: neg | neg learn on|off | neg forget <ticks> | neg max <n> | neg add <a> <b>
+func cmdNeg(ctx *Context, args []string) {
+	if len(args) == 2 && args[0] == "learn" && (args[1] == "on" || args[1] == "off") {
+		ctx.NegLearn = args[1] == "on"
+		if !ctx.NegLearn {
+			clearFloatMap(ctx.NegEvidence)
+		}
+		journalf(ctx, "NEG learn=%v", ctx.NegLearn)
+	} else if len(args) == 3 && args[0] == "add" {
+		if _, ok := ctx.Blocks[negID(args[1], args[2])]; ok {
+			fmt.Println("neg: link already exists")
+			return
+		}
+		ensureSensor(ctx, args[1])
+		ensureSensor(ctx, args[2])
+		nb := NewNegLinkBlock(args[1], args[2])
+		ctx.AddBlock(nb)
+		ctx.BlockLastFire[nb.ID()] = ctx.Tick
+		journalf(ctx, "NEG add %s", nb.name)
+	} else if len(args) == 2 && (args[0] == "forget" || args[0] == "max") {
+		v, err := strconv.Atoi(args[1])
+		if err != nil || v < 0 {
+			fmt.Printf("neg: bad value %q\n", args[1])
+			return
+		}
+		if args[0] == "forget" {
+			ctx.NegForgetAfter = v
+		} else {
+			ctx.NegMax = v
+		}
+		journalf(ctx, "NEG forget=%d max=%d", ctx.NegForgetAfter, ctx.NegMax)
+	} else if len(args) > 0 {
+		fmt.Println("usage: neg | neg learn on|off | neg forget <ticks> | neg max <n> | neg add <a> <b>")
+		return
+	}
+
+	fmt.Printf("Negative links: %d (learning=%v, forget after %d idle ticks, max %d)\n",
+		countBlocksByPrefix(ctx, "NEG:"), ctx.NegLearn, ctx.NegForgetAfter, ctx.NegMax)
+	for _, l := range negLinkList(ctx, 64) {
+		fmt.Printf("           %s\n", l)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import "testing"
+
+func TestPruneNegLinks(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   string
+		sense  []string // tokens made known before pruning
+		idle   int
+		pinned bool
+		kept   bool
+	}{
+		{"known tokens", "1", "2", []string{"1", "2"}, 0, false, true},
+		{"numeric bins", "temp@20..30", "temp@30..40", []string{"temp=25", "temp=35"}, 0, false, true},
+		{"sensor and bin", "1", "temp@20..30", []string{"1", "temp=25"}, 0, false, true},
+		{"unknown token", "1", "9", []string{"1"}, 0, false, false},
+		{"unknown bin", "1", "temp@40..50", []string{"1", "temp=25"}, 0, false, false},
+		{"idle", "1", "2", []string{"1", "2"}, 600, false, false},
+		{"pinned idle", "1", "2", []string{"1", "2"}, 600, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			for _, tok := range tt.sense {
+				ensureSensor(ctx, tok)
+			}
+			nb := NewNegLinkBlock(tt.a, tt.b)
+			ctx.AddBlock(nb)
+			ctx.Tick = 1000
+			ctx.BlockLastFire[nb.ID()] = ctx.Tick - tt.idle
+			if tt.pinned {
+				PinStruct(ctx, nb.name)
+			}
+
+			pruneNegLinks(ctx)
+			if _, ok := ctx.Blocks[nb.ID()]; ok != tt.kept {
+				t.Errorf("kept = %v, want %v", ok, tt.kept)
+			}
+		})
+	}
+}
+
+func TestPruneNegLinksCap(t *testing.T) {
+	ctx := NewContext()
+	ctx.NegMax = 2
+	for _, tok := range []string{"1", "2", "3", "4"} {
+		ensureSensor(ctx, tok)
+	}
+	strengths := map[string]float64{"2": 0.9, "3": 0.5, "4": 0.7}
+	for _, b := range sortedKeys(strengths) {
+		nb := NewNegLinkBlock("1", b)
+		nb.strength = strengths[b]
+		ctx.AddBlock(nb)
+		ctx.BlockLastFire[nb.ID()] = ctx.Tick
+	}
+
+	pruneNegLinks(ctx)
+	for b, want := range map[string]bool{"2": true, "3": false, "4": true} {
+		if _, ok := ctx.Blocks[negID("1", b)]; ok != want {
+			t.Errorf("link 1!>%s kept = %v, want %v", b, ok, want)
+		}
+	}
+}
+
+func TestRefuteNegLink(t *testing.T) {
+	ctx := NewContext()
+	nb := NewNegLinkBlock("1", "2")
+	ctx.AddBlock(nb)
+	for i := 0; i < 2; i++ {
+		refuteNegLink(ctx, "1", "2")
+		if _, ok := ctx.Blocks[nb.ID()]; !ok {
+			t.Fatalf("link removed after %d refutations", i+1)
+		}
+	}
+	refuteNegLink(ctx, "1", "2")
+	if _, ok := ctx.Blocks[nb.ID()]; ok {
+		t.Errorf("link kept after three refutations (strength %.2f)", nb.strength)
+	}
+}
+
+func TestNegLinkSuppressesPredInRunTick(t *testing.T) {
+	ctx := NewContext()
+	if err := InjectStructure(ctx, "[1-2]"); err != nil {
+		t.Fatal(err)
+	}
+	SetTransWeight(ctx, "[1-2]", "3", 2.0)
+	ensureSensor(ctx, "3")
+	ctx.AddBlock(NewNegLinkBlock("2", "3"))
+
+	feedToken(ctx, "1")
+	out := feedToken(ctx, "2")
+
+	if !ctx.ThisStructSet["[1-2]"] {
+		t.Fatal("[1-2] did not fire")
+	}
+	for _, s := range out {
+		if s.Kind == K_PRED && s.Value == "[1-2]->3" {
+			t.Errorf("suppressed prediction emitted by %s", s.From)
+		}
+	}
+	if !negSuppressed(ctx, "[1-2]", "3") || ctx.PendingExpect["[1-2]"] == "3" {
+		t.Errorf("prediction not suppressed: %v armed %v", ctx.NegSuppressed, ctx.PendingExpect)
+	}
+	if ctx.PropIncidents != 0 {
+		t.Errorf("propagation incident: %s", propDiagSummary(ctx.PropDiag, 4))
+	}
+}
+
+func TestNegLearningOptIn(t *testing.T) {
+	for _, on := range []bool{false, true} {
+		ctx := NewContext()
+		ctx.NegLearn = on
+		ctx.LastSens = "2"
+		ctx.PredConf["[1-2]"] = 0.9
+		for i := 0; i < 2; i++ {
+			negFromError(ctx, "[1-2]", "3")
+		}
+		if _, ok := ctx.Blocks[negID("2", "3")]; ok != on {
+			t.Errorf("NegLearn=%v: link learned = %v", on, ok)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Numeric telemetry arrives as "name=value" tokens (e.g. "temp=21.5").
+// Values are recognized by RangeSensorBlocks: receptive fields [lo,hi) that
+// activate with graded mass and name their activation by a bin token like
+// "temp@20..30". Structures and predictions work on these bin tokens.
+
+// parseNumeric splits "name=value" into its parts.
+func parseNumeric(tok string) (name string, v float64, ok bool) {
+	i := strings.LastIndex(tok, "=")
+	if i <= 0 || i == len(tok)-1 {
+		return "", 0, false
+	}
+	v, err := strconv.ParseFloat(tok[i+1:], 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return "", 0, false
+	}
+	return tok[:i], v, true
+}
+
+// fmtNum formats a field bound for use inside a token. '-' is reserved for
+// pair names, so negative numbers are written with an "n" prefix.
+func fmtNum(v float64) string {
+	s := strconv.FormatFloat(v, 'f', -1, 64)
+	return strings.Replace(s, "-", "n", 1)
+}
+
+func parseNum(s string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(s, "n", "-", 1), 64)
+}
+
+func binToken(name string, lo, hi float64) string {
+	return fmt.Sprintf("%s@%s..%s", name, fmtNum(lo), fmtNum(hi))
+}
+
+// parseBinToken reverses binToken.
+func parseBinToken(tok string) (name string, lo, hi float64, ok bool) {
+	i := strings.LastIndex(tok, "@")
+	if i <= 0 {
+		return "", 0, 0, false
+	}
+	bounds := strings.SplitN(tok[i+1:], "..", 2)
+	if len(bounds) != 2 {
+		return "", 0, 0, false
+	}
+	lo, err1 := parseNum(bounds[0])
+	hi, err2 := parseNum(bounds[1])
+	if err1 != nil || err2 != nil || hi <= lo {
+		return "", 0, 0, false
+	}
+	return tok[:i], lo, hi, true
+}
+
+// RangeSensorBlock is a receptive field over one numeric variable.
+type RangeSensorBlock struct {
+	name    string
+	lo, hi  float64
+	sum     float64 // running sum of recognized values (for the predicted value)
+	n       float64
+	errVals []float64 // values that arrived with a misprediction inside this field
+}
+
+func NewRangeSensorBlock(name string, lo, hi float64) *RangeSensorBlock {
+	return &RangeSensorBlock{name: name, lo: lo, hi: hi}
+}
+
+func (b *RangeSensorBlock) token() string { return binToken(b.name, b.lo, b.hi) }
+
+func (b *RangeSensorBlock) ID() string { return "RANGE:" + b.token() }
+
+func (b *RangeSensorBlock) Clone() Block {
+	c := *b
+	c.errVals = append([]float64(nil), b.errVals...)
+	return &c
+}
+
+func (b *RangeSensorBlock) contains(v float64) bool { return v >= b.lo && v < b.hi }
+
+// value returns the number a prediction of this field stands for:
+// the mean of recognized values, or the field center before any were seen.
+func (b *RangeSensorBlock) value() float64 {
+	if b.n > 0 {
+		return b.sum / b.n
+	}
+	return (b.lo + b.hi) / 2
+}
+
+func (b *RangeSensorBlock) React(s Signal, ctx *Context) []Signal {
+	if s.Kind != K_SENS {
+		return nil
+	}
+	name, v, ok := parseNumeric(s.Value)
+	if !ok || name != b.name || !b.contains(v) {
+		return nil
+	}
+	if ctx.LearningEnabled {
+		b.sum += v
+		b.n++
+	}
+
+	// Graded activation: full mass at the center, half mass at the edges.
+	half := (b.hi - b.lo) / 2
+	center := (b.lo + b.hi) / 2
+	mass := s.Mass * (1.0 - 0.5*math.Abs(v-center)/half)
+
+	return []Signal{{
+		Kind:  K_ACT,
+		Value: b.token(),
+		Mass:  mass,
+		Time:  ctx.Tick,
+		From:  b.ID(),
+	}}
+}
+
+func (b *RangeSensorBlock) Tick(ctx *Context) []Signal { return nil }
+
+// ensureNumField returns the receptive field covering v, creating an aligned
+// field of NumInitWidth when the value falls outside all existing ones.
+func ensureNumField(ctx *Context, name string, v float64) (f *RangeSensorBlock, created bool) {
+	for _, f := range ctx.NumFields[name] {
+		if f.contains(v) {
+			return f, false
+		}
+	}
+	w := ctx.NumInitWidth
+	if w <= 0 {
+		w = 10
+	}
+	lo := math.Floor(v/w) * w
+	hi := lo + w
+	// Do not overlap fields produced by earlier splits.
+	for _, g := range ctx.NumFields[name] {
+		if g.lo > v && g.lo < hi {
+			hi = g.lo
+		}
+		if g.hi <= v && g.hi > lo {
+			lo = g.hi
+		}
+	}
+	f = NewRangeSensorBlock(name, lo, hi)
+	addNumField(ctx, f)
+	return f, true
+}
+
+func addNumField(ctx *Context, f *RangeSensorBlock) {
+	fs := append(ctx.NumFields[f.name], f)
+	sort.Slice(fs, func(i, j int) bool { return fs[i].lo < fs[j].lo })
+	ctx.NumFields[f.name] = fs
+	ctx.AddBlock(f)
+}
+
+// numFieldByToken finds the live receptive field named by a bin token.
+func numFieldByToken(ctx *Context, tok string) *RangeSensorBlock {
+	name, lo, hi, ok := parseBinToken(tok)
+	if !ok {
+		return nil
+	}
+	for _, f := range ctx.NumFields[name] {
+		if f.lo == lo && f.hi == hi {
+			return f
+		}
+	}
+	return nil
+}
+
+// sensToken maps a raw sensory value to the token the field reasons about.
+// Numeric values become the bin token of their receptive field; anything else
+// is returned unchanged.
+func sensToken(ctx *Context, raw string) string {
+	name, v, ok := parseNumeric(raw)
+	if !ok {
+		return raw
+	}
+	f, _ := ensureNumField(ctx, name, v)
+	ctx.NumLast[name] = v
+	return f.token()
+}
+
+// noteNumericError records the raw value behind a misprediction whose actual
+// token is a numeric bin.
+func noteNumericError(ctx *Context, actual string) {
+	f := numFieldByToken(ctx, actual)
+	if f == nil {
+		return
+	}
+	if v, ok := ctx.NumLast[f.name]; ok && f.contains(v) {
+		f.errVals = append(f.errVals, v)
+	}
+}
+
+// noteNumericResidual handles a correctly predicted numeric bin: when the
+// actual value is far from the value the field stands for, the bin is too
+// coarse there and the value is recorded as an error inside the field.
+func noteNumericResidual(ctx *Context, pred string) {
+	f := numFieldByToken(ctx, pred)
+	if f == nil {
+		return
+	}
+	v, ok := ctx.NumLast[f.name]
+	if !ok || !f.contains(v) {
+		return
+	}
+	if math.Abs(v-f.value()) > (f.hi-f.lo)/4 {
+		f.errVals = append(f.errVals, v)
+	}
+}
+
+// splitReceptiveFields halves every field in which recent errors cluster.
+// A cluster is at least NumSplitAfter error values with a spread below a
+// quarter of the field width. Fields never shrink below NumMinWidth.
+func splitReceptiveFields(ctx *Context) {
+	names := make([]string, 0, len(ctx.NumFields))
+	for name := range ctx.NumFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, f := range append([]*RangeSensorBlock(nil), ctx.NumFields[name]...) {
+			if len(f.errVals) < ctx.NumSplitAfter {
+				continue
+			}
+			width := f.hi - f.lo
+			if width/2 < ctx.NumMinWidth {
+				f.errVals = f.errVals[:0]
+				continue
+			}
+
+			mean := 0.0
+			for _, v := range f.errVals {
+				mean += v
+			}
+			mean /= float64(len(f.errVals))
+			vr := 0.0
+			for _, v := range f.errVals {
+				vr += (v - mean) * (v - mean)
+			}
+			sd := math.Sqrt(vr / float64(len(f.errVals)))
+			if sd >= width/4 {
+				f.errVals = f.errVals[:0]
+				continue
+			}
+
+			mid := (f.lo + f.hi) / 2
+			left := NewRangeSensorBlock(name, f.lo, mid)
+			right := NewRangeSensorBlock(name, mid, f.hi)
+			removeNumField(ctx, f)
+			addNumField(ctx, left)
+			addNumField(ctx, right)
+
+			ctx.TrainEvents = append(ctx.TrainEvents,
+				fmt.Sprintf("+++ RECEPTIVE FIELD SPLIT %s -> %s + %s (errors clustered at %.2f)",
+					f.token(), left.token(), right.token(), mean))
+
+			heir := left
+			if !left.contains(f.value()) {
+				heir = right
+			}
+			migrateSplitBin(ctx, f.token(), heir.token())
+		}
+	}
+}
+
+// migrateSplitBin hands what was learned on the bin token old of a split
+// field over to heir, the child holding the field's mean value: predictions
+// of old (transition weights, armed expectations, exceptions) and the
+// sensory history move to heir. Structures and negative links built on old
+// cannot be renamed; they are retired with a train event, together with
+// the evidence towards them, and can be learned again on the new bins.
+// Pinned structures are kept.
+func migrateSplitBin(ctx *Context, old, heir string) {
+	for _, id := range sortedKeys(ctx.Blocks) {
+		name := structOfID(id)
+		if nb, ok := ctx.Blocks[id].(*NegLinkBlock); ok {
+			name = nb.name
+		}
+		if name == "" || ctx.Pinned[name] || !mentionsToken(name, old) {
+			continue
+		}
+		if _, ok := ctx.Blocks[id]; !ok {
+			continue // already gone as a dependent
+		}
+		if strings.HasPrefix(id, "NEG:") {
+			removeBlock(ctx, id)
+		} else {
+			evictStructure(ctx, id)
+		}
+		ctx.TrainEvents = append(ctx.TrainEvents, fmt.Sprintf("--- RETIRED %s (bin %s split)", id, old))
+	}
+	for _, m := range []map[string]float64{ctx.SeenPairs, ctx.SeenSeq, ctx.SeenComposes, ctx.SeenGaps, ctx.NegEvidence, ctx.ExceptEvidence} {
+		for k := range m {
+			if mentionsToken(k, old) {
+				delete(m, k)
+			}
+		}
+	}
+
+	rename := func(tok string) string {
+		if tok == old {
+			return heir
+		}
+		return tok
+	}
+	for _, st := range sortedKeys(ctx.TransCounts) {
+		m := ctx.TransCounts[st]
+		if w, ok := m[old]; ok {
+			delete(m, old)
+			m[heir] += w
+			refreshPrediction(ctx, st)
+		}
+	}
+	for _, m := range []map[string]string{ctx.PendingExpect, ctx.ExpectCtx, ctx.BaseHitCtx, ctx.LastByChan, ctx.PrevByChan} {
+		for k, v := range m {
+			m[k] = rename(v)
+		}
+	}
+	for i := range ctx.TimedExpect {
+		ctx.TimedExpect[i].Token = rename(ctx.TimedExpect[i].Token)
+	}
+	for i := range ctx.SensLog {
+		ctx.SensLog[i].Tok = rename(ctx.SensLog[i].Tok)
+	}
+	ctx.LastSens, ctx.PrevSens = rename(ctx.LastSens), rename(ctx.PrevSens)
+	for _, k := range sortedKeys(ctx.Exceptions) {
+		ex := ctx.Exceptions[k]
+		if ex.Tok != old && ex.Context != old {
+			continue
+		}
+		delete(ctx.Exceptions, k)
+		ex.Tok, ex.Context = rename(ex.Tok), rename(ex.Context)
+		ctx.Exceptions[exceptKey(ex.Struct, ex.Context)] = ex
+	}
+	for _, m := range []map[string]int{ctx.GapBase, ctx.LastSeenAt, ctx.LastInterval, ctx.IntervalRun} {
+		if v, ok := m[old]; ok {
+			delete(m, old)
+			m[heir] = v
+		}
+	}
+}
+
+// mentionsToken reports whether tok occurs in a structure name or evidence
+// key as a whole token, i.e. delimited by the punctuation that builds those
+// names ("[a-b]", "(a>b)", "(a>b@2)", "~a/3", "a|b", "base||x").
+func mentionsToken(s, tok string) bool {
+	const delims = "[]()-|>!@~/"
+	for i := 0; ; {
+		j := strings.Index(s[i:], tok)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(tok)
+		if (start == 0 || strings.IndexByte(delims, s[start-1]) >= 0) &&
+			(end == len(s) || strings.IndexByte(delims, s[end]) >= 0) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func removeNumField(ctx *Context, f *RangeSensorBlock) {
+	fs := ctx.NumFields[f.name]
+	out := fs[:0]
+	for _, g := range fs {
+		if g != f {
+			out = append(out, g)
+		}
+	}
+	ctx.NumFields[f.name] = out
+
+	id := f.ID()
+	delete(ctx.Blocks, id)
+	delete(ctx.BlockLastFire, id)
+	order := ctx.Order[:0]
+	for _, x := range ctx.Order {
+		if x != id {
+			order = append(order, x)
+		}
+	}
+	ctx.Order = order
+}
+
+// PredictedValue returns the numeric value a structure currently predicts,
+// when its prediction is a numeric bin.
+func PredictedValue(ctx *Context, st string) (name string, v float64, ok bool) {
+	tok := ctx.BestPred[st]
+	if f := numFieldByToken(ctx, tok); f != nil {
+		return f.name, f.value(), true
+	}
+	name, lo, hi, ok := parseBinToken(tok)
+	if !ok {
+		return "", 0, false
+	}
+	return name, (lo + hi) / 2, true
+}
+
+// numericPredictions lists structures with numeric predictions for the board.
+func numericPredictions(ctx *Context, n int) []string {
+	out := make([]string, 0, n)
+	for st := range ctx.BestPred {
+		if ctx.PredConf[st] < 0.25 {
+			continue
+		}
+		if name, v, ok := PredictedValue(ctx, st); ok {
+			out = append(out, fmt.Sprintf("%s⇒%s≈%.2f(st=%.2f)", st, name, v, ctx.PredConf[st]))
+		}
+	}
+	sort.Strings(out)
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
@@ -0,0 +1,135 @@
+package main
+
+import "testing"
+
+func TestBinTokenRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		lo, hi float64
+		tok    string
+	}{
+		{"temp", 20, 30, "temp@20..30"},
+		{"temp", -10, 0, "temp@n10..0"},
+		{"temp", -30, -20, "temp@n30..n20"},
+		{"temp", -0.5, 0.25, "temp@n0.5..0.25"},
+		{"audio:vol", 1.5, 2, "audio:vol@1.5..2"},
+		{"a@b", -1, 1, "a@b@n1..1"},
+	}
+	for _, tt := range tests {
+		tok := binToken(tt.name, tt.lo, tt.hi)
+		if tok != tt.tok {
+			t.Errorf("binToken(%s, %v, %v) = %q, want %q", tt.name, tt.lo, tt.hi, tok, tt.tok)
+		}
+		name, lo, hi, ok := parseBinToken(tok)
+		if !ok || name != tt.name || lo != tt.lo || hi != tt.hi {
+			t.Errorf("parseBinToken(%q) = %s, %v, %v, %v; want %s, %v, %v", tok, name, lo, hi, ok, tt.name, tt.lo, tt.hi)
+		}
+	}
+}
+
+func TestParseBinTokenRejects(t *testing.T) {
+	for _, tok := range []string{"temp", "@1..2", "temp@1", "temp@30..20", "temp@5..5", "temp@x..1", "temp@1..n", "temp=23"} {
+		if _, _, _, ok := parseBinToken(tok); ok {
+			t.Errorf("parseBinToken(%q) accepted", tok)
+		}
+	}
+}
+
+func TestFmtNum(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{0, "0"},
+		{12.25, "12.25"},
+		{-3, "n3"},
+		{-0.125, "n0.125"},
+	}
+	for _, tt := range tests {
+		s := fmtNum(tt.v)
+		if s != tt.want {
+			t.Errorf("fmtNum(%v) = %q, want %q", tt.v, s, tt.want)
+		}
+		if v, err := parseNum(s); err != nil || v != tt.v {
+			t.Errorf("parseNum(%q) = %v, %v; want %v", s, v, err, tt.v)
+		}
+	}
+}
+
+func TestSplitMigratesBin(t *testing.T) {
+	ctx := NewContext()
+	ensureSensor(ctx, "1")
+	ensureSensor(ctx, "temp=22")
+	f := numFieldByToken(ctx, "temp@20..30")
+	f.sum, f.n = 66, 3
+	f.errVals = []float64{21, 21.5, 22, 22.5}
+
+	ctx.AddBlock(NewCoActBlock("1", "temp@20..30"))
+	ctx.AddBlock(NewCoActBlock("1", "temp@20..300"))
+	ctx.TransCounts["(0>1)"] = map[string]float64{"temp@20..30": 1, "2": 0.2}
+	ctx.BestPred["(0>1)"] = "temp@20..30"
+	ctx.SeenSeq["temp@20..30>1"] = 0.5
+	ctx.PendingExpect["(0>1)"] = "temp@20..30"
+	ctx.LastSens = "temp@20..30"
+
+	splitReceptiveFields(ctx)
+
+	const heir = "temp@20..25"
+	if numFieldByToken(ctx, heir) == nil || numFieldByToken(ctx, "temp@25..30") == nil {
+		t.Fatalf("field not split: %v", ctx.NumFields["temp"])
+	}
+	if w := ctx.TransCounts["(0>1)"][heir]; w != 1 || ctx.BestPred["(0>1)"] != heir {
+		t.Errorf("prediction not moved: %v best %s", ctx.TransCounts["(0>1)"], ctx.BestPred["(0>1)"])
+	}
+	if ctx.PendingExpect["(0>1)"] != heir || ctx.LastSens != heir {
+		t.Errorf("expectation %s / last %s not moved to %s", ctx.PendingExpect["(0>1)"], ctx.LastSens, heir)
+	}
+	if _, ok := ctx.Blocks["COACT:[1-temp@20..30]"]; ok {
+		t.Errorf("structure on the split bin kept")
+	}
+	if _, ok := ctx.Blocks["COACT:[1-temp@20..300]"]; !ok {
+		t.Errorf("structure on another bin retired")
+	}
+	if _, ok := ctx.SeenSeq["temp@20..30>1"]; ok {
+		t.Errorf("evidence on the split bin kept")
+	}
+	retired := false
+	for _, ev := range ctx.TrainEvents {
+		retired = retired || ev == "--- RETIRED COACT:[1-temp@20..30] (bin temp@20..30 split)"
+	}
+	if !retired {
+		t.Errorf("no retirement event in %v", ctx.TrainEvents)
+	}
+}
+
+func TestRangeSensorLearnsOnlyWhileLearning(t *testing.T) {
+	for _, learning := range []bool{false, true} {
+		ctx := NewContext()
+		ctx.LearningEnabled = learning
+		f := NewRangeSensorBlock("temp", 20, 30)
+		if out := f.React(Signal{Kind: K_SENS, Value: "temp=22", Mass: 1}, ctx); len(out) != 1 {
+			t.Fatalf("field did not activate")
+		}
+		if got := f.n > 0; got != learning {
+			t.Errorf("learning=%v: mean updated = %v", learning, got)
+		}
+	}
+}
+
+func TestMentionsToken(t *testing.T) {
+	cases := []struct {
+		s    string
+		want bool
+	}{
+		{"temp@20..30", true},
+		{"COACT:[1-temp@20..30]", true},
+		{"(a>temp@20..30)", true},
+		{"COACT:[1-temp@20..300]", false},
+		{"xtemp@20..30", false},
+	}
+	for _, c := range cases {
+		if got := mentionsToken(c.s, "temp@20..30"); got != c.want {
+			t.Errorf("mentionsToken(%q) = %v, want %v", c.s, got, c.want)
+		}
+	}
+}
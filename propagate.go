@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PropDiag describes how propagation settled in the last tick.
+type PropDiag struct {
+	Tick      int
+	Rounds    int
+	Signals   int      // signals processed
+	Mass      float64  // total mass processed
+	Cycles    []string // re-emissions dropped as cycles ("from|kind|value")
+	Truncated []string // signals left unprocessed when a budget ran out
+	Reason    string   // "" when the queue drained, otherwise the budget that stopped it
+}
+
+// lineage is the chain of signals that led to a queued signal, nearest cause first.
+type lineage struct {
+	key    string // from|kind|value
+	parent *lineage
+}
+
+// contains reports whether key occurs anywhere in the chain.
+func (l *lineage) contains(key string) bool {
+	for ; l != nil; l = l.parent {
+		if l.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// propagate runs reactions until the queue is empty.
+// It is bounded by MaxRounds, SignalBudget and MassBudget. A signal equal
+// in (from, kind, value) to one of its own causes is a re-emission through
+// a loop and is dropped as a cycle; the same signal reached along another
+// path, in the same or a later round, is separate evidence and is kept.
+// Actions are returned separately as candidates for arbitration.
+// With PriorityQueue set, each round is processed strongest signal first
+// instead of in insertion order.
+func propagate(ctx *Context, queue []Signal) (allOut, actionCands []Signal) {
+	allOut = make([]Signal, 0, 256)
+	actionCands = make([]Signal, 0, 4)
+	diag := PropDiag{Tick: ctx.Tick}
+	causes := make([]*lineage, len(queue))
+
+	for r := 0; len(queue) > 0; r++ {
+		if r >= ctx.MaxRounds {
+			diag.Reason = fmt.Sprintf("rounds>=%d", ctx.MaxRounds)
+			break
+		}
+		diag.Rounds = r + 1
+		if ctx.PriorityQueue {
+			sort.Stable(byStrength{queue, causes})
+		}
+		nextQueue := make([]Signal, 0, 256)
+		nextCauses := make([]*lineage, 0, 256)
+
+		for i, raw := range queue {
+			if diag.Signals >= ctx.SignalBudget || diag.Mass >= ctx.MassBudget {
+				if diag.Signals >= ctx.SignalBudget {
+					diag.Reason = fmt.Sprintf("signals>=%d", ctx.SignalBudget)
+				} else {
+					diag.Reason = fmt.Sprintf("mass>=%.0f", ctx.MassBudget)
+				}
+				nextQueue = append(nextQueue, queue[i:]...)
+				nextCauses = append(nextCauses, causes[i:]...)
+				break
+			}
+
+			key := raw.From + "|" + string(raw.Kind) + "|" + raw.Value
+			if causes[i].contains(key) {
+				diag.Cycles = append(diag.Cycles, key)
+				continue
+			}
+			self := &lineage{key: key, parent: causes[i]}
+
+			s := applyInhibition(ctx, raw)
+			if s.Mass <= 0 {
+				continue
+			}
+			diag.Signals++
+			diag.Mass += s.Mass
+
+			if s.Kind == K_STRUCT || s.Kind == K_ACTION || s.Kind == K_ACT || s.Kind == K_INHIB {
+				if s.From != "" {
+					if _, ok := ctx.Blocks[s.From]; ok {
+						ctx.BlockLastFire[s.From] = ctx.Tick
+						ctx.FireCount[s.From]++
+					}
+				}
+			}
+
+			// Actions do not propagate immediately: they are collected as
+			// candidates and compete once propagation has settled.
+			if s.Kind == K_ACTION {
+				actionCands = append(actionCands, s)
+				continue
+			}
+
+			// Negative links suppress a prediction already passed on this tick.
+			if s.Kind == K_PRED {
+				notePredIssued(ctx, s)
+			}
+			if s.Kind == K_INHIB && strings.HasPrefix(s.From, "NEG:") {
+				allOut = noteNegSuppression(ctx, s, allOut)
+			}
+
+			if s.Kind == K_STRUCT {
+				ctx.RecentStruct = append(ctx.RecentStruct, s)
+
+				// Accumulate activation mass for competition.
+				ctx.ThisStructSet[s.Value] = true
+				ctx.ThisStructMass[s.Value] += s.Mass
+
+				// Emit prediction if not strongly suppressed.
+				if pred := ctx.BestPred[s.Value]; pred != "" && !predInhibited(ctx, s.Value, pred) {
+					nextQueue = append(nextQueue, Signal{
+						Kind:  K_PRED,
+						Value: fmt.Sprintf("%s->%s", s.Value, pred),
+						Mass:  0.6,
+						Time:  ctx.Tick,
+						From:  "FIELD:MODEL",
+					})
+					nextCauses = append(nextCauses, self)
+				}
+			}
+
+			// Blocks shed for lack of energy do not react (see metabolism.go).
+			for _, id := range ctx.Order {
+				if !mayReact(ctx, id) {
+					continue
+				}
+				out := ctx.Blocks[id].React(s, ctx)
+				chargeReaction(ctx, id, len(out))
+				for _, o := range out {
+					nextQueue = append(nextQueue, o)
+					nextCauses = append(nextCauses, self)
+				}
+			}
+
+			allOut = append(allOut, s)
+		}
+
+		queue, causes = nextQueue, nextCauses
+		if diag.Reason != "" {
+			break
+		}
+	}
+
+	for _, s := range queue {
+		diag.Truncated = append(diag.Truncated, fmt.Sprintf("%s:%s<-%s", s.Kind, s.Value, s.From))
+	}
+	if len(diag.Cycles) > 0 || len(diag.Truncated) > 0 {
+		ctx.PropIncidents++
+	}
+	ctx.PropDiag = diag
+	return allOut, actionCands
+}
+
+// kindRank orders signal kinds for tie-breaking: input and errors first,
+// then activations, structures, predictions, and finally outputs.
+var kindRank = map[Kind]int{
+	K_SENS:   0,
+	K_ERR:    1,
+	K_DRIVE:  2,
+	K_ACT:    3,
+	K_STRUCT: 4,
+	K_PRED:   5,
+	K_INHIB:  6,
+	K_NOTE:   7,
+	K_ACTION: 8,
+}
+
+// strongerSignal reports whether a is processed before b in priority order:
+// higher mass first, then kind rank, then preferStructName on the value,
+// then the emitting block. Remaining ties keep insertion order.
+func strongerSignal(a, b Signal) bool {
+	if a.Mass != b.Mass {
+		return a.Mass > b.Mass
+	}
+	if ra, rb := kindRank[a.Kind], kindRank[b.Kind]; ra != rb {
+		return ra < rb
+	}
+	if a.Value != b.Value {
+		return preferStructName(a.Value, b.Value)
+	}
+	return a.From < b.From
+}
+
+// byStrength sorts a queue and its causes together in priority order.
+type byStrength struct {
+	q      []Signal
+	causes []*lineage
+}
+
+func (b byStrength) Len() int           { return len(b.q) }
+func (b byStrength) Less(i, j int) bool { return strongerSignal(b.q[i], b.q[j]) }
+func (b byStrength) Swap(i, j int) {
+	b.q[i], b.q[j] = b.q[j], b.q[i]
+	b.causes[i], b.causes[j] = b.causes[j], b.causes[i]
+}
+
+// cmdQueue handles: queue [fifo|mass]
+func cmdQueue(ctx *Context, args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "fifo":
+			ctx.PriorityQueue = false
+		case "mass":
+			ctx.PriorityQueue = true
+		default:
+			fmt.Println("usage: queue [fifo|mass]")
+			return
+		}
+	}
+	mode := "fifo"
+	if ctx.PriorityQueue {
+		mode = "mass"
+	}
+	fmt.Printf("Propagation queue = %s\n", mode)
+}
+
+// propDiagSummary renders the last tick's diagnostics for the board.
+func propDiagSummary(d PropDiag, max int) string {
+	s := fmt.Sprintf("rounds=%d signals=%d mass=%.2f", d.Rounds, d.Signals, d.Mass)
+	if d.Reason != "" {
+		s += " stopped=" + d.Reason
+	}
+	if len(d.Cycles) > 0 {
+		cyc := uniqueSorted(d.Cycles)
+		if len(cyc) > max {
+			cyc = cyc[:max]
+		}
+		s += fmt.Sprintf(" cycles=%v", cyc)
+	}
+	if len(d.Truncated) > 0 {
+		tr := d.Truncated
+		if len(tr) > max {
+			tr = tr[:max]
+		}
+		s += fmt.Sprintf(" truncated=%d %v", len(d.Truncated), tr)
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPropagateBudgets(t *testing.T) {
+	tests := []struct {
+		name        string
+		rounds      int
+		signals     int
+		mass        float64
+		wantReason  string
+		wantSignals int
+	}{
+		{"drains", 32, 4096, 2048, "", 6},
+		{"round budget", 1, 4096, 2048, "rounds>=1", 3},
+		{"signal budget", 32, 2, 2048, "signals>=2", 2},
+		{"mass budget", 32, 4096, 1.5, "mass>=2", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			ctx.MaxRounds, ctx.SignalBudget, ctx.MassBudget = tt.rounds, tt.signals, tt.mass
+			queue := make([]Signal, 0, 3)
+			for _, tok := range []string{"1", "2", "3"} {
+				ensureSensor(ctx, tok)
+				queue = append(queue, Signal{Kind: K_SENS, Value: tok, Mass: 1, From: "USER"})
+			}
+
+			propagate(ctx, queue)
+			d := ctx.PropDiag
+			if d.Reason != tt.wantReason || d.Signals != tt.wantSignals {
+				t.Errorf("stopped=%q after %d signals, want %q after %d", d.Reason, d.Signals, tt.wantReason, tt.wantSignals)
+			}
+			if truncated := len(d.Truncated) > 0; truncated != (tt.wantReason != "") {
+				t.Errorf("truncated = %v with reason %q", d.Truncated, d.Reason)
+			}
+			if incident := ctx.PropIncidents > 0; incident != (tt.wantReason != "") {
+				t.Errorf("incidents = %d with reason %q", ctx.PropIncidents, d.Reason)
+			}
+		})
+	}
+}
+
+// relayBlock answers a K_NOTE with one value by emitting another.
+type relayBlock struct {
+	id string
+	on map[string]string // received value -> emitted value
+}
+
+func (b *relayBlock) ID() string { return b.id }
+
+func (b *relayBlock) Clone() Block { c := *b; return &c }
+
+func (b *relayBlock) React(s Signal, ctx *Context) []Signal {
+	if v, ok := b.on[s.Value]; ok && s.Kind == K_NOTE {
+		return []Signal{{Kind: K_NOTE, Value: v, Mass: 1, From: b.id}}
+	}
+	return nil
+}
+
+func (b *relayBlock) Tick(ctx *Context) []Signal { return nil }
+
+func TestPropagateCycles(t *testing.T) {
+	tests := []struct {
+		name       string
+		relays     map[string]map[string]string
+		wantCycles []string
+		wantZ      int // how often RELAY:c|z was processed
+	}{
+		{"loop", map[string]map[string]string{
+			"RELAY:a": {"x": "y"},
+			"RELAY:b": {"y": "x"},
+		}, []string{"RELAY:a|NOTE|y"}, 0},
+		{"same signal along two paths", map[string]map[string]string{
+			"RELAY:c": {"x": "z", "q": "z"},
+			"RELAY:d": {"x": "q"},
+		}, nil, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			for _, id := range sortedKeys(tt.relays) {
+				ctx.AddBlock(&relayBlock{id: id, on: tt.relays[id]})
+			}
+			out, _ := propagate(ctx, []Signal{{Kind: K_NOTE, Value: "x", Mass: 1, From: "USER"}})
+
+			if !slices.Equal(ctx.PropDiag.Cycles, tt.wantCycles) {
+				t.Errorf("cycles = %v, want %v", ctx.PropDiag.Cycles, tt.wantCycles)
+			}
+			if incident := ctx.PropIncidents > 0; incident != (len(tt.wantCycles) > 0) {
+				t.Errorf("incidents = %d", ctx.PropIncidents)
+			}
+			z := 0
+			for _, s := range out {
+				if s.From == "RELAY:c" && s.Value == "z" {
+					z++
+				}
+			}
+			if z != tt.wantZ {
+				t.Errorf("z processed %d times, want %d", z, tt.wantZ)
+			}
+		})
+	}
+}
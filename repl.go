@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// session is the state of one command stream: the interactive REPL or a
+// scenario script. Both feed every line through handle, so a command
+// behaves the same whether it is typed or read from a file.
+type session struct {
+	ctx         *Context
+	interactive bool // demo, investor and autoboard apply only at the REPL
+
+	sleepMs      int
+	autoBoard    bool
+	investorMode bool
+	demoRunning  bool
+
+	last     EpisodeReport // latest episode, shown by board
+	boardCtx *Context      // context shown by board (the demo's after a demo)
+}
+
+// newSession returns a session on ctx. Interactive sessions pace episodes
+// and print the board after each one; scripts do neither.
+func newSession(ctx *Context, interactive bool) *session {
+	s := &session{ctx: ctx, interactive: interactive, boardCtx: ctx}
+	if interactive {
+		s.sleepMs = 12
+		s.autoBoard = true
+	}
+	return s
+}
+
+// handle runs one non-empty line: an argument command, a mode line or an
+// input episode. It reports whether the stream should stop.
+func (s *session) handle(line string) (quit bool) {
+	if runCommand(s.ctx, strings.Fields(line)) {
+		return false
+	}
+	if handled, quit := s.modeLine(line); handled {
+		return quit
+	}
+	s.last = RunEpisodeLine(s.ctx, line, s.investorMode, s.demoRunning, s.sleepMs, s.autoBoard)
+	s.boardCtx = s.ctx
+	maybeAutoSleep(s.ctx)
+	return false
+}
+
+// modeLine applies the mode lines (train, test, reset, board, ...). Lines
+// that only make sense interactively are skipped in a script. It reports
+// whether line was a mode line and whether the stream should stop.
+func (s *session) modeLine(line string) (handled, quit bool) {
+	ctx := s.ctx
+	cmd := strings.ToLower(line)
+	switch cmd {
+	case "quit", "exit":
+		return true, true
+	case "train":
+		ctx.LearningEnabled, ctx.LearnStruct, ctx.LearnPred = true, true, true
+		fmt.Println("MODE = TRAIN (learning enabled)")
+	case "test":
+		ctx.LearningEnabled, ctx.LearnStruct, ctx.LearnPred = false, false, false
+		fmt.Println("MODE = TEST (learning disabled)")
+	case "reset":
+		resetEpisodeBoundary(ctx)
+		s.last = EpisodeReport{}
+		fmt.Println("Reset episode boundary")
+	case "board":
+		printBoard(s.boardCtx, s.last.Structs, s.last.Actions, s.last.Errs, nil)
+	case "color on":
+		colorLogs = true
+		fmt.Println("Color logs = ON")
+	case "color off":
+		colorLogs = false
+		fmt.Println("Color logs = OFF")
+	case "predlog on":
+		showPredEvents = true
+		fmt.Println("Pred event log = ON")
+	case "predlog off":
+		showPredEvents = false
+		fmt.Println("Pred event log = OFF")
+	case "pairs on":
+		ctx.DemoFocusPairsOnly = true
+		fmt.Println("Pairs-only mode = ON (UI-only: hides non-[a-b] in episode/board output)")
+	case "pairs off":
+		ctx.DemoFocusPairsOnly = false
+		fmt.Println("Pairs-only mode = OFF (UI-only)")
+	case "demo", "investor on", "investor off", "autoboard on", "autoboard off":
+		if !s.interactive {
+			fmt.Printf("scenario: %q is interactive only, skipped\n", line)
+			return true, false
+		}
+		switch cmd {
+		case "demo":
+			s.demo()
+		case "investor on":
+			s.investorMode = true
+			s.autoBoard = false
+			s.sleepMs = 0
+			fmt.Println("Investor mode = ON (concise event log, autoboard off, no sleep)")
+		case "investor off":
+			s.investorMode = false
+			fmt.Println("Investor mode = OFF")
+		case "autoboard on":
+			s.autoBoard = true
+			fmt.Println("Auto board = ON")
+		case "autoboard off":
+			s.autoBoard = false
+			fmt.Println("Auto board = OFF")
+		}
+	default:
+		return false, false
+	}
+	return true, false
+}
+
+// demo runs the scripted three-step demonstration on a fresh context and
+// leaves that context on the board.
+func (s *session) demo() {
+	prevInvestorMode := s.investorMode
+	prevAutoBoard := s.autoBoard
+	prevSleepMs := s.sleepMs
+	prevDemoRunning := s.demoRunning
+
+	s.demoRunning = true
+	s.investorMode = true
+	s.autoBoard = false
+	s.sleepMs = 0
+	fmt.Println("Demo: investor mode ON, running scripted sequence...")
+
+	demoCtx := NewContext()
+	demoCtx.LearningEnabled = true
+	demoCtx.DemoFocusPairsOnly = true
+	demoCtx.DisableSeq = true
+
+	s.last = EpisodeReport{}
+
+	demoCtx.SuppressPredLog = true
+	demoCtx.LearnStruct = true
+	demoCtx.LearnPred = false
+
+	run := func(line string) {
+		s.last = RunEpisodeLine(demoCtx, line, s.investorMode, s.demoRunning, s.sleepMs, false)
+	}
+	board := func() {
+		printBoard(demoCtx, s.last.Structs, s.last.Actions, s.last.Errs, nil)
+		s.boardCtx = demoCtx
+	}
+
+	fmt.Println("DEMO STEP 1/3: ACCUMULATION -> CRYSTALLIZATION")
+	run("1 2 1 2 1 2 1 2 1 2 1 2   2 3 2 3 2 3 2 3 2 3 2 3")
+	board()
+	fmt.Println("NOTE: Step 1 reports accumulation and block crystallization (new blocks). STRUCT signals appear in Step 2.")
+
+	demoCtx.SuppressPredLog = false
+	demoCtx.LearnStruct = false
+	demoCtx.LearnPred = true
+	demoCtx.DemoFocusPairsOnly = true
+
+	fmt.Println("DEMO STEP 2/3: STRUCTURES -> PREDICTION")
+	run("1 2 3 1 2 3 1 2 3 1 2 3 1 2 3 1 2 3")
+	board()
+
+	fmt.Println("DEMO STEP 3/3: MISPREDICTION -> INHIBITION + ERROR-BOOST -> FAST RE-LEARN")
+
+	demoCtx.LearningEnabled = false
+	demoCtx.LearnStruct = false
+	demoCtx.LearnPred = false
+	run("1 2 3")
+	demoPulse(demoCtx, "after prime episode: 1 2 3")
+
+	demoCtx.LearningEnabled = true
+	demoCtx.LearnStruct = false
+	demoCtx.LearnPred = true
+	run("1 2 4 1 2 4 1 2 4 1 2 4 1 2 4 1 2 4")
+	demoPulse(demoCtx, "after clean switch episode: 1 2 4")
+	printBoard(demoCtx, s.last.Structs, s.last.Actions, s.last.Errs, nil)
+
+	demoCtx.LearningEnabled = true
+	demoCtx.LearnStruct = false
+	demoCtx.LearnPred = true
+	run("1 2 4")
+	demoPulse(demoCtx, "verify #1 (train): 1 2 4")
+
+	demoCtx.LearningEnabled = false
+	demoCtx.LearnStruct = false
+	demoCtx.LearnPred = false
+	run("1 2 4")
+	demoPulse(demoCtx, "verify #2 (test): 1 2 4")
+	board()
+
+	pairs := countBlocksByPrefix(demoCtx, "COACT:")
+	seqs := countBlocksByPrefix(demoCtx, "SEQ:")
+	comps := countBlocksByPrefix(demoCtx, "COMPOSE:")
+	acts := countBlocksByPrefix(demoCtx, "ACTIONBLOCK:")
+
+	fmt.Printf(
+		"DEMO SUMMARY: learned pairs=%d | seqs=%d | composes=%d | actionLinks=%d | blocks=%d\n",
+		pairs, seqs, comps, acts, len(demoCtx.Blocks),
+	)
+
+	demoCtx.DemoFocusPairsOnly = false
+
+	s.investorMode = prevInvestorMode
+	s.autoBoard = prevAutoBoard
+	s.sleepMs = prevSleepMs
+	s.demoRunning = prevDemoRunning
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Corruption is a parametrized input corruption model. level is the
+// per-token corruption probability; vocab is the pool for inserted or
+// substituted tokens.
+type Corruption struct {
+	Name  string
+	Apply func(tokens []string, level float64, rng *rand.Rand, vocab []string) []string
+}
+
+var corruptions = []Corruption{
+	{Name: "deletion", Apply: corruptDelete},
+	{Name: "insertion", Apply: corruptInsert},
+	{Name: "substitution", Apply: corruptSubstitute},
+	{Name: "duplication", Apply: corruptDuplicate},
+	{Name: "burst", Apply: corruptBurst},
+}
+
+func corruptDelete(tokens []string, level float64, rng *rand.Rand, vocab []string) []string {
+	out := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if rng.Float64() >= level {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func corruptInsert(tokens []string, level float64, rng *rand.Rand, vocab []string) []string {
+	out := make([]string, 0, len(tokens)+4)
+	for _, t := range tokens {
+		out = append(out, t)
+		if rng.Float64() < level {
+			out = append(out, vocab[rng.Intn(len(vocab))])
+		}
+	}
+	return out
+}
+
+func corruptSubstitute(tokens []string, level float64, rng *rand.Rand, vocab []string) []string {
+	out := make([]string, len(tokens))
+	for i, t := range tokens {
+		if rng.Float64() < level {
+			t = vocab[rng.Intn(len(vocab))]
+		}
+		out[i] = t
+	}
+	return out
+}
+
+func corruptDuplicate(tokens []string, level float64, rng *rand.Rand, vocab []string) []string {
+	out := make([]string, 0, len(tokens)+4)
+	for _, t := range tokens {
+		out = append(out, t)
+		if rng.Float64() < level {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// corruptBurst replaces runs of 3 tokens with noise; bursts start with
+// probability level/3, so the expected share of corrupted tokens is about level.
+func corruptBurst(tokens []string, level float64, rng *rand.Rand, vocab []string) []string {
+	const burstLen = 3
+	out := make([]string, len(tokens))
+	left := 0
+	for i, t := range tokens {
+		if left == 0 && rng.Float64() < level/burstLen {
+			left = burstLen
+		}
+		if left > 0 {
+			t = vocab[rng.Intn(len(vocab))]
+			left--
+		}
+		out[i] = t
+	}
+	return out
+}
+
+// RobustPoint is the outcome of training on one corruption model at one level.
+type RobustPoint struct {
+	Level       float64
+	Structs     int     // learned structure blocks
+	Stable      float64 // share of clean predictions (BestPred) preserved
+	TrainErrs   int     // ERR events while training on corrupted input
+	TestErrs    int     // ERR events on the clean stream afterwards (learning off)
+	CooldownAbs int     // mispredictions absorbed by ErrCooldown while training
+	BoostTicks  int     // ticks under error boost (ErrTTL) while training
+}
+
+// RobustOptions configures RunRobustness.
+type RobustOptions struct {
+	Levels []float64
+	Reps   int // corrupted copies of the stream fed as separate episodes
+	Seed   int64
+}
+
+// RunRobustness trains a fresh field on corrupted copies of stream for every
+// model and level and measures how learning degrades against a clean run.
+// All output of the inner runs is discarded.
+func RunRobustness(stream []string, models []Corruption, opt RobustOptions) map[string][]RobustPoint {
+	stdout := os.Stdout
+	if devnull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+		os.Stdout = devnull
+		defer func() {
+			os.Stdout = stdout
+			devnull.Close()
+		}()
+	}
+
+	vocab := uniqueSorted(stream)
+	clean := robustTrain(stream, Corruption{Name: "none"}, 0, opt, vocab)
+	cleanPreds := confidentPreds(clean.ctx)
+
+	res := make(map[string][]RobustPoint, len(models))
+	for _, m := range models {
+		for _, lvl := range opt.Levels {
+			run := robustTrain(stream, m, lvl, opt, vocab)
+			p := run.point
+			kept := 0
+			for st, tok := range cleanPreds {
+				if run.ctx.BestPred[st] == tok {
+					kept++
+				}
+			}
+			if len(cleanPreds) > 0 {
+				p.Stable = float64(kept) / float64(len(cleanPreds))
+			}
+			res[m.Name] = append(res[m.Name], p)
+		}
+	}
+	return res
+}
+
+type robustRun struct {
+	ctx   *Context
+	point RobustPoint
+}
+
+func robustTrain(stream []string, m Corruption, level float64, opt RobustOptions, vocab []string) robustRun {
+	rng := rand.New(rand.NewSource(opt.Seed))
+	ctx := NewContext()
+	ctx.AutoSleep = false
+	p := RobustPoint{Level: level}
+
+	for r := 0; r < opt.Reps; r++ {
+		toks := stream
+		if m.Apply != nil && level > 0 {
+			toks = m.Apply(stream, level, rng, vocab)
+		}
+		rep := RunEpisodeLine(ctx, strings.Join(toks, " "), false, false, 0, false)
+		p.TrainErrs += len(rep.Errs)
+	}
+	p.CooldownAbs = ctx.CooldownHits
+	p.BoostTicks = ctx.ErrBoostTicks
+
+	ctx.LearningEnabled, ctx.LearnStruct, ctx.LearnPred = false, false, false
+	rep := RunEpisodeLine(ctx, strings.Join(stream, " "), false, false, 0, false)
+	p.TestErrs = len(rep.Errs)
+
+	for _, pre := range []string{"COACT:", "SEQ:", "COMPOSE:", "GAP:", "RHYTHM:"} {
+		p.Structs += countBlocksByPrefix(ctx, pre)
+	}
+	return robustRun{ctx: ctx, point: p}
+}
+
+func confidentPreds(ctx *Context) map[string]string {
+	out := make(map[string]string)
+	for _, st := range sortedStructsWithPred(ctx) {
+		if ctx.PredConf[st] >= 0.25 {
+			out[st] = ctx.BestPred[st]
+		}
+	}
+	return out
+}
+
+// printDegradation renders one ASCII curve per metric: a row per level,
+// bars scaled to the largest value across all models.
+func printDegradation(res map[string][]RobustPoint) {
+	names := sortedKeys(res)
+	metrics := []struct {
+		title string
+		get   func(RobustPoint) float64
+		pct   bool
+	}{
+		{"structures learned", func(p RobustPoint) float64 { return float64(p.Structs) }, false},
+		{"clean predictions kept", func(p RobustPoint) float64 { return p.Stable }, true},
+		{"ERR while training", func(p RobustPoint) float64 { return float64(p.TrainErrs) }, false},
+		{"ERR absorbed by cooldown", func(p RobustPoint) float64 { return float64(p.CooldownAbs) }, false},
+		{"ticks under error boost", func(p RobustPoint) float64 { return float64(p.BoostTicks) }, false},
+		{"ERR on clean stream", func(p RobustPoint) float64 { return float64(p.TestErrs) }, false},
+	}
+
+	const width = 30
+	for _, m := range metrics {
+		maxV := 0.0
+		for _, n := range names {
+			for _, p := range res[n] {
+				if v := m.get(p); v > maxV {
+					maxV = v
+				}
+			}
+		}
+		if m.pct {
+			maxV = 1
+		}
+		cprintf(C_MAGENTA+C_BOLD, "CURVE: %s\n", m.title)
+		for _, n := range names {
+			for _, p := range res[n] {
+				v := m.get(p)
+				bar := 0
+				if maxV > 0 {
+					bar = int(v/maxV*width + 0.5)
+				}
+				val := fmt.Sprintf("%.0f", v)
+				if m.pct {
+					val = fmt.Sprintf("%.0f%%", v*100)
+				}
+				fmt.Printf("  %-12s %4.0f%% |%-*s| %s\n", n, p.Level*100, width, strings.Repeat("#", bar), val)
+			}
+		}
+	}
+}
+
+// cmdRobust handles:
+//
+//	robust [-model=all|name,...] [-levels=0,0.1,...] [-reps=N] [-seed=N] <tokens...>
+func cmdRobust(ctx *Context, args []string) {
+	opt := RobustOptions{Levels: []float64{0, 0.05, 0.1, 0.2, 0.3}, Reps: 4, Seed: 1}
+	models := corruptions
+	stream := make([]string, 0, len(args))
+
+	for _, a := range args {
+		switch {
+		case strings.HasPrefix(a, "-model="):
+			v := strings.TrimPrefix(a, "-model=")
+			if v == "all" {
+				continue
+			}
+			models = nil
+			for _, name := range strings.Split(v, ",") {
+				found := false
+				for _, c := range corruptions {
+					if c.Name == name {
+						models = append(models, c)
+						found = true
+					}
+				}
+				if !found {
+					fmt.Printf("robust: unknown model %q\n", name)
+					return
+				}
+			}
+		case strings.HasPrefix(a, "-levels="):
+			opt.Levels = nil
+			for _, f := range strings.Split(strings.TrimPrefix(a, "-levels="), ",") {
+				v, err := strconv.ParseFloat(f, 64)
+				if err != nil || v < 0 || v > 1 {
+					fmt.Printf("robust: bad level %q\n", f)
+					return
+				}
+				opt.Levels = append(opt.Levels, v)
+			}
+			sort.Float64s(opt.Levels)
+		case strings.HasPrefix(a, "-reps="):
+			v, err := strconv.Atoi(strings.TrimPrefix(a, "-reps="))
+			if err != nil || v <= 0 {
+				fmt.Printf("robust: bad reps %q\n", a)
+				return
+			}
+			opt.Reps = v
+		case strings.HasPrefix(a, "-seed="):
+			v, err := strconv.ParseInt(strings.TrimPrefix(a, "-seed="), 10, 64)
+			if err != nil {
+				fmt.Printf("robust: bad seed %q\n", a)
+				return
+			}
+			opt.Seed = v
+		default:
+			stream = append(stream, a)
+		}
+	}
+	if len(stream) < 2 {
+		fmt.Println("usage: robust [-model=all|deletion,insertion,substitution,duplication,burst] [-levels=0,0.1,0.2] [-reps=N] [-seed=N] <tokens...>")
+		return
+	}
+
+	cprintf(C_MAGENTA+C_BOLD, "ROBUSTNESS: %d tokens x %d reps, levels=%v seed=%d (fresh field per run)\n",
+		len(stream), opt.Reps, opt.Levels, opt.Seed)
+	printDegradation(RunRobustness(stream, models, opt))
+}
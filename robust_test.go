@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestCorruptions(t *testing.T) {
+	in := []string{"1", "2", "3", "4", "5", "6"}
+	vocab := []string{"x"}
+	tests := []struct {
+		model string
+		level float64
+		want  []string
+	}{
+		{"deletion", 0, in},
+		{"deletion", 1, []string{}},
+		{"insertion", 0, in},
+		{"insertion", 1, []string{"1", "x", "2", "x", "3", "x", "4", "x", "5", "x", "6", "x"}},
+		{"substitution", 0, in},
+		{"substitution", 1, []string{"x", "x", "x", "x", "x", "x"}},
+		{"duplication", 0, in},
+		{"duplication", 1, []string{"1", "1", "2", "2", "3", "3", "4", "4", "5", "5", "6", "6"}},
+		{"burst", 0, in},
+		{"burst", 3, []string{"x", "x", "x", "x", "x", "x"}}, // a burst starts at every chance
+	}
+	byName := make(map[string]Corruption)
+	for _, c := range corruptions {
+		byName[c.Name] = c
+	}
+	for _, tt := range tests {
+		c, ok := byName[tt.model]
+		if !ok {
+			t.Fatalf("no corruption model %q", tt.model)
+		}
+		got := c.Apply(in, tt.level, rand.New(rand.NewSource(1)), vocab)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s@%.1f = %v, want %v", tt.model, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestCorruptionsDeterministic(t *testing.T) {
+	in := []string{"1", "2", "3", "1", "2", "3", "1", "2", "4"}
+	orig := slices.Clone(in)
+	vocab := []string{"1", "2", "3", "4"}
+	for _, c := range corruptions {
+		a := c.Apply(in, 0.3, rand.New(rand.NewSource(7)), vocab)
+		b := c.Apply(in, 0.3, rand.New(rand.NewSource(7)), vocab)
+		if !slices.Equal(a, b) {
+			t.Errorf("%s: same seed gave %v and %v", c.Name, a, b)
+		}
+		if !slices.Equal(in, orig) {
+			t.Fatalf("%s modified its input", c.Name)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// RunScenario executes a scenario script line by line, exactly as typed at
+// the REPL: argument commands (gen, clock, env, sleep, ...), mode lines
+// (train, test, reset, board, ...) and input episodes of tokens. Blank lines
+// and lines starting with '#' are ignored; quit or exit ends the script.
+func RunScenario(ctx *Context, r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	sess := newSession(ctx, false)
+	n := 0
+	for sc.Scan() {
+		n++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cprintf(C_GRAY, "scenario:%d> %s\n", n, line)
+		if sess.handle(line) {
+			break
+		}
+	}
+	return sc.Err()
+}
+
+// cmdScenario handles: scenario <file>
+func cmdScenario(ctx *Context, args []string) {
+	if len(args) != 1 {
+		fmt.Println("usage: scenario <file>")
+		return
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		fmt.Println("scenario:", err)
+		return
+	}
+	defer f.Close()
+	if err := RunScenario(ctx, f); err != nil {
+		fmt.Println("scenario:", err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunScenarioModeLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		learning bool
+		sensors  []string
+	}{
+		{"train", "test\ntrain\n1 2\n", true, []string{"1", "2"}},
+		{"test", "train\ntest\n1 2\n", false, []string{"1", "2"}},
+		{"reset and board", "reset\nboard\n1\n", true, []string{"1"}},
+		{"quit stops", "1\nquit\n2\n", true, []string{"1"}},
+		{"interactive skipped", "investor on\nautoboard off\n1\n", true, []string{"1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			if err := RunScenario(ctx, strings.NewReader(tt.script)); err != nil {
+				t.Fatal(err)
+			}
+			if ctx.LearningEnabled != tt.learning {
+				t.Errorf("LearningEnabled = %v, want %v", ctx.LearningEnabled, tt.learning)
+			}
+			if got := sortedKeys(ctx.Sensors); strings.Join(got, " ") != strings.Join(tt.sensors, " ") {
+				t.Errorf("sensors = %v, want %v", got, tt.sensors)
+			}
+		})
+	}
+}
+
+func TestModeLinesIgnoreCase(t *testing.T) {
+	defer func(c, p bool) { colorLogs, showPredEvents = c, p }(colorLogs, showPredEvents)
+	colorLogs, showPredEvents = false, false
+
+	for _, interactive := range []bool{false, true} {
+		ctx := NewContext()
+		sess := newSession(ctx, interactive)
+		for _, line := range []string{"Color On", "PREDLOG ON", "Pairs Off", "Test"} {
+			if sess.handle(line) {
+				t.Fatalf("%q stopped the session", line)
+			}
+		}
+		if !colorLogs || !showPredEvents || ctx.DemoFocusPairsOnly || ctx.LearningEnabled {
+			t.Errorf("interactive=%v: color=%v predlog=%v pairs=%v learning=%v",
+				interactive, colorLogs, showPredEvents, ctx.DemoFocusPairsOnly, ctx.LearningEnabled)
+		}
+		if len(ctx.Sensors) != 0 {
+			t.Errorf("interactive=%v: mode lines fed as input: %v", interactive, sortedKeys(ctx.Sensors))
+		}
+		colorLogs, showPredEvents = false, false
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+)
+
+// ScheduledSignal is a signal waiting in the delivery queue.
+type ScheduledSignal struct {
+	Due int // tick on which the signal is delivered
+	Seq int // insertion order, breaks ties between equal Due
+	Sig Signal
+}
+
+// SignalHeap is a min-heap of scheduled signals ordered by (Due, Seq).
+type SignalHeap []ScheduledSignal
+
+func (h SignalHeap) Len() int { return len(h) }
+func (h SignalHeap) Less(i, j int) bool {
+	if h[i].Due != h[j].Due {
+		return h[i].Due < h[j].Due
+	}
+	return h[i].Seq < h[j].Seq
+}
+func (h SignalHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *SignalHeap) Push(x any)   { *h = append(*h, x.(ScheduledSignal)) }
+func (h *SignalHeap) Pop() any {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// Schedule queues s for delivery delay ticks from now (at least one).
+// Blocks call it from React or Tick to model delayed effects.
+func (ctx *Context) Schedule(s Signal, delay int) {
+	if delay < 1 {
+		delay = 1
+	}
+	ctx.SchedSeq++
+	heap.Push(&ctx.Scheduled, ScheduledSignal{Due: ctx.Tick + delay, Seq: ctx.SchedSeq, Sig: s})
+}
+
+// deliverDue pops every signal due on or before the current tick.
+// Delivered signals are re-stamped with the current tick.
+func deliverDue(ctx *Context) []Signal {
+	var out []Signal
+	for ctx.Scheduled.Len() > 0 && ctx.Scheduled[0].Due <= ctx.Tick {
+		ss := heap.Pop(&ctx.Scheduled).(ScheduledSignal)
+		ss.Sig.Time = ctx.Tick
+		out = append(out, ss.Sig)
+	}
+	return out
+}
+
+// scheduledSummary lists up to max pending deliveries, soonest first.
+func scheduledSummary(ctx *Context, max int) []string {
+	pending := append(SignalHeap(nil), ctx.Scheduled...)
+	sort.Sort(pending)
+	out := make([]string, 0, max)
+	for _, ss := range pending {
+		if len(out) >= max {
+			break
+		}
+		out = append(out, fmt.Sprintf("%s:%s@t=%d", ss.Sig.Kind, ss.Sig.Value, ss.Due))
+	}
+	return out
+}
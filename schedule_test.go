@@ -0,0 +1,64 @@
+package main
+
+import (
+	"container/heap"
+	"testing"
+)
+
+func TestSignalHeapOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []ScheduledSignal
+		want []string
+	}{
+		{"by due", []ScheduledSignal{{Due: 5, Seq: 1, Sig: Signal{Value: "c"}}, {Due: 2, Seq: 2, Sig: Signal{Value: "a"}}, {Due: 3, Seq: 3, Sig: Signal{Value: "b"}}}, []string{"a", "b", "c"}},
+		{"ties by seq", []ScheduledSignal{{Due: 4, Seq: 3, Sig: Signal{Value: "c"}}, {Due: 4, Seq: 1, Sig: Signal{Value: "a"}}, {Due: 4, Seq: 2, Sig: Signal{Value: "b"}}}, []string{"a", "b", "c"}},
+		{"mixed", []ScheduledSignal{{Due: 7, Seq: 1, Sig: Signal{Value: "d"}}, {Due: 1, Seq: 4, Sig: Signal{Value: "b"}}, {Due: 1, Seq: 2, Sig: Signal{Value: "a"}}, {Due: 3, Seq: 3, Sig: Signal{Value: "c"}}}, []string{"a", "b", "c", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h SignalHeap
+			for _, ss := range tt.in {
+				heap.Push(&h, ss)
+			}
+			for i, want := range tt.want {
+				if got := heap.Pop(&h).(ScheduledSignal).Sig.Value; got != want {
+					t.Fatalf("pop %d = %s, want %s", i, got, want)
+				}
+			}
+			if h.Len() != 0 {
+				t.Errorf("%d signals left", h.Len())
+			}
+		})
+	}
+}
+
+func TestDeliverDue(t *testing.T) {
+	ctx := NewContext()
+	ctx.Tick = 10
+	ctx.Schedule(Signal{Value: "late"}, 3)
+	ctx.Schedule(Signal{Value: "first"}, 1)
+	ctx.Schedule(Signal{Value: "second"}, 0) // clamped to one tick
+	ctx.Schedule(Signal{Value: "mid"}, 2)
+
+	for _, step := range []struct {
+		tick int
+		want []string
+	}{
+		{10, nil},
+		{11, []string{"first", "second"}},
+		{12, []string{"mid"}},
+		{14, []string{"late"}},
+	} {
+		ctx.Tick = step.tick
+		got := deliverDue(ctx)
+		if len(got) != len(step.want) {
+			t.Fatalf("t=%d delivered %v, want %v", step.tick, got, step.want)
+		}
+		for i, s := range got {
+			if s.Value != step.want[i] || s.Time != step.tick {
+				t.Errorf("t=%d delivery %d = %s@%d, want %s@%d", step.tick, i, s.Value, s.Time, step.want[i], step.tick)
+			}
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SleepReport describes what an offline consolidation phase changed.
+type SleepReport struct {
+	Ticks          int
+	Episodes       int
+	Strengthened   []string
+	Merged         []string
+	PrunedEvidence int
+	PrunedTrans    int
+	BlocksBefore   int
+	BlocksAfter    int
+	ConfBefore     float64 // mean PredConf over predictive structures
+	ConfAfter      float64
+	EnergyBefore   float64
+	EnergyAfter    float64
+}
+
+// recordEpisode keeps the most recent EpisodeStoreMax episodes for replay.
+func recordEpisode(ctx *Context, tokens []string) {
+	if ctx.EpisodeStoreMax <= 0 || len(tokens) == 0 {
+		return
+	}
+	ctx.EpisodeStore = append(ctx.EpisodeStore, append([]string(nil), tokens...))
+	if n := len(ctx.EpisodeStore) - ctx.EpisodeStoreMax; n > 0 {
+		ctx.EpisodeStore = append(ctx.EpisodeStore[:0], ctx.EpisodeStore[n:]...)
+	}
+}
+
+// Sleep consolidation limits: sleep lifts a confident weight by at most 10%
+// and never past sleepBoostCap, and a sequence is merged into its pair only
+// when both carry at least sleepMergeMin weight on the shared prediction.
+const (
+	sleepBoostCap = 1.5
+	sleepMergeMin = 1.0
+)
+
+// Sleep runs an offline consolidation phase of at most ticks ticks.
+//
+//  1. Replay: stored episodes are fed back (oldest first) with predictive
+//     learning on and structural learning off. The replay runs on a copy of
+//     the context, so no action fires, no energy is spent and Tick does not
+//     advance; only the transition weights it learned are kept.
+//  2. Strengthen: confident predictions (PredConf >= 0.6) get a 10% weight
+//     boost, up to sleepBoostCap.
+//  3. Merge: a sequence (a>b) whose pair [a-b] exists and predicts the same
+//     token, both with at least sleepMergeMin weight, is folded into the
+//     pair, together with its action link.
+//  4. Prune: unfinished structure evidence below 0.5 and transition weights
+//     below 0.10 are dropped.
+//
+// Every structure whose weights steps 2-4 changed has its prediction
+// re-derived afterwards.
+//
+// Sleeping also restores energy to EnergyMax.
+func Sleep(ctx *Context, ticks int) SleepReport {
+	rep := SleepReport{
+		BlocksBefore: len(ctx.Blocks),
+		ConfBefore:   meanPredConf(ctx),
+		EnergyBefore: ctx.Energy,
+	}
+
+	// 1) Replay
+	if len(ctx.EpisodeStore) > 0 && ticks > 0 {
+		replay := cloneContext(ctx)
+		replay.LearningEnabled, replay.LearnStruct, replay.LearnPred = true, false, true
+		replay.SuppressPredLog = true
+		for _, ep := range ctx.EpisodeStore {
+			if rep.Ticks >= ticks {
+				break
+			}
+			resetEpisodeBoundary(replay)
+			for _, item := range ep {
+				if rep.Ticks >= ticks {
+					break
+				}
+				feedItem(replay, item, "REPLAY")
+				rep.Ticks++
+			}
+			rep.Episodes++
+		}
+		ctx.TransCounts, ctx.BestPred, ctx.PredConf = replay.TransCounts, replay.BestPred, replay.PredConf
+	}
+	resetEpisodeBoundary(ctx)
+
+	touched := make(map[string]bool)
+
+	// 2) Strengthen
+	for _, st := range sortedStructsWithPred(ctx) {
+		best := ctx.BestPred[st]
+		if ctx.PredConf[st] < 0.6 || ctx.TransCounts[st] == nil {
+			continue
+		}
+		w := ctx.TransCounts[st][best]
+		if w >= sleepBoostCap {
+			continue
+		}
+		ctx.TransCounts[st][best] = min(w*1.10, sleepBoostCap)
+		touched[st] = true
+		rep.Strengthened = append(rep.Strengthened, fmt.Sprintf("%s⇒%s", st, best))
+	}
+
+	// 3) Merge redundant sequences into their pairs
+	for _, st := range sortedStructsWithPred(ctx) {
+		if !strings.HasPrefix(st, "(") || strings.Contains(st, "@") || ctx.Pinned[st] {
+			continue
+		}
+		inner := strings.TrimSuffix(strings.TrimPrefix(st, "("), ")")
+		ab := strings.SplitN(inner, ">", 2)
+		if len(ab) != 2 {
+			continue
+		}
+		pair := canonicalPairName(ab[0], ab[1])
+		best := ctx.BestPred[st]
+		if _, ok := ctx.Blocks["COACT:"+pair]; !ok || ctx.BestPred[pair] != best {
+			continue
+		}
+		if ctx.TransCounts[st][best] < sleepMergeMin || ctx.TransCounts[pair][best] < sleepMergeMin {
+			continue
+		}
+
+		if ctx.TransCounts[pair] == nil {
+			ctx.TransCounts[pair] = make(map[string]float64)
+		}
+		for tok, w := range ctx.TransCounts[st] {
+			nw := ctx.TransCounts[pair][tok] + w
+			if nw > 3.00 {
+				nw = 3.00
+			}
+			ctx.TransCounts[pair][tok] = nw
+		}
+		touched[pair] = true
+		delete(touched, st)
+		// Action links may have been renamed; compositions on st go with it.
+		for _, dep := range dependentsOf(ctx, st) {
+			if strings.HasPrefix(dep, "COMPOSE:") {
+				evictStructure(ctx, dep)
+			} else {
+				removeBlock(ctx, dep)
+			}
+		}
+		removeBlock(ctx, "SEQ:"+st)
+		dropExceptions(ctx, st)
+		delete(ctx.TransCounts, st)
+		delete(ctx.BestPred, st)
+		delete(ctx.PredConf, st)
+		rep.Merged = append(rep.Merged, fmt.Sprintf("%s→%s", st, pair))
+	}
+
+	// 4) Prune weak evidence
+	for _, m := range []map[string]float64{ctx.SeenPairs, ctx.SeenSeq, ctx.SeenComposes, ctx.SeenGaps} {
+		for k, v := range m {
+			if v >= 0 && v < 0.5 {
+				delete(m, k)
+				rep.PrunedEvidence++
+			}
+		}
+	}
+	for st, m := range ctx.TransCounts {
+		for tok, w := range m {
+			if w < 0.10 {
+				delete(m, tok)
+				touched[st] = true
+				rep.PrunedTrans++
+			}
+		}
+		if len(m) == 0 {
+			delete(ctx.TransCounts, st)
+		}
+	}
+
+	for _, st := range sortedKeys(touched) {
+		refreshPrediction(ctx, st)
+	}
+
+	ctx.Energy = ctx.EnergyMax
+
+	rep.BlocksAfter = len(ctx.Blocks)
+	rep.ConfAfter = meanPredConf(ctx)
+	rep.EnergyAfter = ctx.Energy
+	return rep
+}
+
+// removeBlock deletes a single block by ID, keeping Order consistent.
+func removeBlock(ctx *Context, id string) {
+	if _, ok := ctx.Blocks[id]; !ok {
+		return
+	}
+	delete(ctx.Blocks, id)
+	delete(ctx.BlockLastFire, id)
+	delete(ctx.FireCount, id)
+	order := ctx.Order[:0]
+	for _, x := range ctx.Order {
+		if x != id {
+			order = append(order, x)
+		}
+	}
+	ctx.Order = order
+}
+
+func sortedStructsWithPred(ctx *Context) []string {
+	out := make([]string, 0, len(ctx.BestPred))
+	for st, tok := range ctx.BestPred {
+		if tok != "" {
+			out = append(out, st)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func meanPredConf(ctx *Context) float64 {
+	sts := sortedStructsWithPred(ctx)
+	if len(sts) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, st := range sts {
+		sum += ctx.PredConf[st]
+	}
+	return sum / float64(len(sts))
+}
+
+func printSleepReport(rep SleepReport, reason string) {
+	cprintf(C_MAGENTA+C_BOLD, "SLEEP (%s): replayed %d episodes in %d ticks\n", reason, rep.Episodes, rep.Ticks)
+	fmt.Printf("           blocks %d -> %d | mean conf %.2f -> %.2f | energy %.2f -> %.2f\n",
+		rep.BlocksBefore, rep.BlocksAfter, rep.ConfBefore, rep.ConfAfter, rep.EnergyBefore, rep.EnergyAfter)
+	if len(rep.Strengthened) > 0 {
+		cprintf(C_GREEN, "           STRENGTHENED: %v\n", rep.Strengthened)
+	}
+	if len(rep.Merged) > 0 {
+		cprintf(C_CYAN, "           MERGED: %v\n", rep.Merged)
+	}
+	fmt.Printf("           PRUNED: evidence=%d transitions=%d\n", rep.PrunedEvidence, rep.PrunedTrans)
+}
+
+// maybeAutoSleep consolidates when energy runs low.
+func maybeAutoSleep(ctx *Context) {
+	if !ctx.AutoSleep || ctx.Energy >= ctx.SleepBelowEnergy || len(ctx.EpisodeStore) == 0 {
+		return
+	}
+	printSleepReport(Sleep(ctx, ctx.SleepTicks), fmt.Sprintf("auto, energy<%.2f", ctx.SleepBelowEnergy))
+}
+
+// cmdSleep handles: sleep [ticks] | sleep auto on|off
+func cmdSleep(ctx *Context, args []string) {
+	if len(args) == 2 && args[0] == "auto" {
+		ctx.AutoSleep = args[1] == "on"
+		fmt.Printf("Auto sleep = %v (energy below %.2f)\n", ctx.AutoSleep, ctx.SleepBelowEnergy)
+		return
+	}
+	ticks := ctx.SleepTicks
+	if len(args) > 0 {
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v <= 0 {
+			fmt.Printf("sleep: bad tick count %q\n", args[0])
+			return
+		}
+		ticks = v
+	}
+	if len(ctx.EpisodeStore) == 0 {
+		fmt.Println("sleep: no stored episodes to replay")
+	}
+	printSleepReport(Sleep(ctx, ticks), "manual")
+}
@@ -0,0 +1,112 @@
+package main
+
+import "testing"
+
+func TestSleepRefreshesPredictions(t *testing.T) {
+	tests := []struct {
+		name     string
+		weights  map[string]float64
+		wantBest string
+	}{
+		{"strengthened", map[string]float64{"3": 1.0}, "3"},
+		{"best pruned", map[string]float64{"3": 0.09, "4": 0.08}, ""},
+		{"runner-up pruned", map[string]float64{"3": 0.5, "4": 0.05}, "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			if err := InjectStructure(ctx, "(1>2)"); err != nil {
+				t.Fatal(err)
+			}
+			for tok, w := range tt.weights {
+				SetTransWeight(ctx, "(1>2)", tok, w)
+			}
+			rep := Sleep(ctx, 0)
+
+			if got := ctx.BestPred["(1>2)"]; got != tt.wantBest {
+				t.Errorf("BestPred = %q, want %q", got, tt.wantBest)
+			}
+			want := gatedConf(ctx.TransCounts["(1>2)"], tt.wantBest)
+			if got := ctx.PredConf["(1>2)"]; got != want {
+				t.Errorf("PredConf = %.3f, want %.3f from the weights", got, want)
+			}
+			if rep.ConfAfter != meanPredConf(ctx) {
+				t.Errorf("ConfAfter = %.3f, want %.3f", rep.ConfAfter, meanPredConf(ctx))
+			}
+		})
+	}
+}
+
+func TestSleepReplayHasNoSideEffects(t *testing.T) {
+	ctx := NewContext()
+	trainCycle(ctx, []string{"1", "2", "3"}, 6)
+	recordEpisode(ctx, []string{"1", "2", "4", "1", "2", "4"})
+	spent := func() (sum float64) {
+		for _, u := range ctx.EnergyUse {
+			sum += u.Spent
+		}
+		return sum
+	}
+	tick, used, journal := ctx.Tick, spent(), len(ctx.Journal)
+	fired := ctx.FireCount["ACTIONBLOCK:ACT_ON_(1>2)<-(1>2)"]
+	before := ctx.TransCounts["[1-2]"]["4"]
+
+	rep := Sleep(ctx, 6)
+
+	if rep.Ticks != 6 {
+		t.Errorf("replayed %d ticks, want 6", rep.Ticks)
+	}
+	if ctx.TransCounts["[1-2]"]["4"] <= before {
+		t.Errorf("replay learned nothing: [1-2]->4 = %.2f", ctx.TransCounts["[1-2]"]["4"])
+	}
+	if ctx.Tick != tick || spent() != used || len(ctx.Journal) != journal {
+		t.Errorf("tick %d->%d, energy used %.2f->%.2f, journal %d->%d",
+			tick, ctx.Tick, used, spent(), journal, len(ctx.Journal))
+	}
+	if got := ctx.FireCount["ACTIONBLOCK:ACT_ON_(1>2)<-(1>2)"]; got != fired {
+		t.Errorf("action fired during replay: %d -> %d", fired, got)
+	}
+}
+
+func TestSleepStrengthenIsCapped(t *testing.T) {
+	ctx := NewContext()
+	if err := InjectStructure(ctx, "(1>2)"); err != nil {
+		t.Fatal(err)
+	}
+	SetTransWeight(ctx, "(1>2)", "3", 1.4)
+	for i := 0; i < 5; i++ {
+		Sleep(ctx, 0)
+	}
+	if got := ctx.TransCounts["(1>2)"]["3"]; got != sleepBoostCap {
+		t.Errorf("weight after repeated sleep = %.3f, want %.2f", got, sleepBoostCap)
+	}
+}
+
+func TestSleepMergeNeedsEvidence(t *testing.T) {
+	tests := []struct {
+		name   string
+		w      float64
+		merged bool
+	}{
+		{"weak", 0.5, false},
+		{"established", 1.2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			for _, st := range []string{"[1-2]", "(1>2)"} {
+				if err := InjectStructure(ctx, st); err != nil {
+					t.Fatal(err)
+				}
+				SetTransWeight(ctx, st, "3", tt.w)
+			}
+			rep := Sleep(ctx, 0)
+			if got := len(rep.Merged) == 1; got != tt.merged {
+				t.Errorf("merged = %v, want %v", rep.Merged, tt.merged)
+			}
+			if _, ok := ctx.Blocks["SEQ:(1>2)"]; ok == tt.merged {
+				t.Errorf("sequence block kept = %v", ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+)
+
+// cloneContext returns an independent copy of the whole context (knowledge,
+// runtime state and block internals) for a tentative run, such as a
+// generative rollout, that must leave ctx untouched. Blocks are copied by
+// their Clone method; everything else reachable from the context keeps its
+// state in exported fields and is copied by reflection. Pointers shared
+// between maps (e.g. RANGE blocks in Blocks and NumFields) stay shared in
+// the copy. The copy has no random source of its own: the caller seeds one
+// when it needs it, so ctx's stream is never advanced by the tentative run.
+func cloneContext(ctx *Context) *Context {
+	c := deepCopy(reflect.ValueOf(ctx), make(map[uintptr]reflect.Value)).Interface().(*Context)
+	c.Rand = nil
+	return c
+}
+
+var (
+	randType   = reflect.TypeOf((*rand.Rand)(nil))
+	blockIface = reflect.TypeOf((*Block)(nil)).Elem()
+)
+
+// deepCopy copies maps, slices, pointers and interfaces recursively.
+// Blocks are cloned; any other struct must not hide reference-typed state
+// in unexported fields, which deepCopy could not reach.
+func deepCopy(v reflect.Value, seen map[uintptr]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || v.Type() == randType {
+			return v
+		}
+		if c, ok := seen[v.Pointer()]; ok {
+			return c
+		}
+		if v.Type().Implements(blockIface) {
+			c := reflect.ValueOf(v.Interface().(Block).Clone())
+			seen[v.Pointer()] = c
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		seen[v.Pointer()] = c
+		c.Elem().Set(deepCopy(v.Elem(), seen))
+		return c
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem(), seen))
+		return c
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		it := v.MapRange()
+		for it.Next() {
+			c.SetMapIndex(it.Key(), deepCopy(it.Value(), seen))
+		}
+		return c
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Cap())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), seen))
+		}
+		return c
+
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(deepCopy(v.Field(i), seen))
+				continue
+			}
+			switch v.Field(i).Kind() {
+			case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+				panic(fmt.Sprintf("cloneContext: %s.%s cannot be copied", v.Type(), v.Type().Field(i).Name))
+			}
+		}
+		return c
+	}
+	return v
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Stochastic mode is opt-in: while ctx.Rand is nil every decision is
+// deterministic. Once seeded, the same seed and input reproduce the same run.
+
+// SetSeed enables stochastic mode with a fresh generator and journals the seed.
+func SetSeed(ctx *Context, seed int64) {
+	ctx.Seed = seed
+	ctx.Rand = rand.New(rand.NewSource(seed))
+	ctx.RandDraws = 0
+	journalf(ctx, "SEED %d", seed)
+}
+
+// ClearSeed returns to deterministic mode.
+func ClearSeed(ctx *Context) {
+	ctx.Rand = nil
+	journalf(ctx, "SEED off")
+}
+
+// rnd draws from ctx.Rand and counts the draw so the digest covers RNG state.
+func rnd(ctx *Context) float64 {
+	ctx.RandDraws++
+	return ctx.Rand.Float64()
+}
+
+// journalf appends a tick-stamped entry to ctx.Journal.
+func journalf(ctx *Context, format string, args ...any) {
+	ctx.Journal = append(ctx.Journal, fmt.Sprintf("t=%03d ", ctx.Tick)+fmt.Sprintf(format, args...))
+}
+
+// softmaxWinner draws the winner from ThisStructMass with probability
+// proportional to exp(mass/Temperature). Candidates are visited in
+// preferStructName order so the draw is reproducible.
+func softmaxWinner(ctx *Context) (string, float64) {
+	cands := sortedKeys(ctx.ThisStructMass)
+	if len(cands) == 0 {
+		return "", 0
+	}
+	sort.SliceStable(cands, func(i, j int) bool { return preferStructName(cands[i], cands[j]) })
+
+	t := ctx.Temperature
+	if t <= 0 {
+		t = 1e-6
+	}
+	maxM := math.Inf(-1)
+	for _, st := range cands {
+		maxM = math.Max(maxM, ctx.ThisStructMass[st])
+	}
+	ws := make([]float64, len(cands))
+	sum := 0.0
+	for i, st := range cands {
+		ws[i] = math.Exp((ctx.ThisStructMass[st] - maxM) / t)
+		sum += ws[i]
+	}
+	r := rnd(ctx) * sum
+	for i, st := range cands {
+		r -= ws[i]
+		if r < 0 {
+			return st, ctx.ThisStructMass[st]
+		}
+	}
+	last := cands[len(cands)-1]
+	return last, ctx.ThisStructMass[last]
+}
+
+// exploreAction occasionally proposes a random attached action as a
+// low-mass candidate, so untried actions can earn reward.
+func exploreAction(ctx *Context) []Signal {
+	if ctx.Rand == nil || ctx.ExploreRate <= 0 || rnd(ctx) >= ctx.ExploreRate {
+		return nil
+	}
+	ids := make([]string, 0, 8)
+	for _, id := range sortedKeys(ctx.Blocks) {
+		if strings.HasPrefix(id, "ACTIONBLOCK:") {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	id := ids[int(rnd(ctx)*float64(len(ids)))%len(ids)]
+	ab := ctx.Blocks[id].(*ActionBlock)
+	if ctx.PredEvents != nil {
+		ctx.PredEvents = append(ctx.PredEvents, fmt.Sprintf("EXPLORE %s", ab.actionName))
+	}
+	return []Signal{{Kind: K_ACTION, Value: ab.actionName, Mass: 0.5, Time: ctx.Tick, From: id}}
+}
+
+// samplePred draws the armed expectation of st from its transition weights
+// instead of always taking BestPred.
+func samplePred(ctx *Context, st string) string {
+	best := ctx.BestPred[st]
+	if ctx.Rand == nil || !ctx.SamplePreds {
+		return best
+	}
+	m := ctx.TransCounts[st]
+	toks := sortedKeys(m)
+	sum := 0.0
+	for _, tok := range toks {
+		sum += m[tok]
+	}
+	if sum <= 0 {
+		return best
+	}
+	r := rnd(ctx) * sum
+	for _, tok := range toks {
+		r -= m[tok]
+		if r < 0 {
+			return tok
+		}
+	}
+	return best
+}
+
+// noisyTokens corrupts an episode: each token is dropped, swapped with its
+// successor, or substituted by another known token with the configured rates.
+func noisyTokens(ctx *Context, tokens []string) ([]string, []string) {
+	if ctx.Rand == nil || ctx.NoiseDrop+ctx.NoiseSwap+ctx.NoiseSubst <= 0 {
+		return tokens, nil
+	}
+	vocab := sortedKeys(ctx.Sensors)
+	for _, t := range tokens {
+		if !ctx.Sensors[t] {
+			vocab = append(vocab, t)
+		}
+	}
+	out := make([]string, 0, len(tokens))
+	var notes []string
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch r := rnd(ctx); {
+		case r < ctx.NoiseDrop:
+			notes = append(notes, fmt.Sprintf("drop@%d:%s", i, tok))
+			continue
+		case r < ctx.NoiseDrop+ctx.NoiseSwap && i+1 < len(tokens):
+			notes = append(notes, fmt.Sprintf("swap@%d:%s<>%s", i, tok, tokens[i+1]))
+			out = append(out, tokens[i+1], tok)
+			i++
+			continue
+		case r < ctx.NoiseDrop+ctx.NoiseSwap+ctx.NoiseSubst && len(vocab) > 1:
+			sub := vocab[int(rnd(ctx)*float64(len(vocab)))%len(vocab)]
+			if sub != tok {
+				notes = append(notes, fmt.Sprintf("subst@%d:%s->%s", i, tok, sub))
+				tok = sub
+			}
+		}
+		out = append(out, tok)
+	}
+	return out, notes
+}
+
+// cmdSeed handles: seed [N|off]
+func cmdSeed(ctx *Context, args []string) {
+	if len(args) > 0 {
+		if args[0] == "off" {
+			ClearSeed(ctx)
+		} else {
+			v, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				fmt.Printf("seed: bad seed %q\n", args[0])
+				return
+			}
+			SetSeed(ctx, v)
+		}
+	}
+	if ctx.Rand == nil {
+		fmt.Println("Stochastic mode = OFF (deterministic)")
+		return
+	}
+	fmt.Printf("Stochastic mode = ON seed=%d draws=%d temp=%.2f explore=%.2f sample=%v noise(drop=%.2f swap=%.2f subst=%.2f)\n",
+		ctx.Seed, ctx.RandDraws, ctx.Temperature, ctx.ExploreRate, ctx.SamplePreds, ctx.NoiseDrop, ctx.NoiseSwap, ctx.NoiseSubst)
+}
+
+// cmdStoch handles: stoch key=value...  (temp, explore, sample, drop, swap, subst)
+func cmdStoch(ctx *Context, args []string) {
+	if len(args) == 0 {
+		fmt.Println("usage: stoch temp=T explore=P sample=on|off drop=P swap=P subst=P")
+		return
+	}
+	for _, a := range args {
+		kv := strings.SplitN(a, "=", 2)
+		if len(kv) != 2 {
+			fmt.Printf("stoch: expected key=value, got %q\n", a)
+			return
+		}
+		if kv[0] == "sample" {
+			ctx.SamplePreds = kv[1] == "on"
+			journalf(ctx, "STOCH sample=%v", ctx.SamplePreds)
+			continue
+		}
+		v, err := strconv.ParseFloat(kv[1], 64)
+		if err != nil || v < 0 {
+			fmt.Printf("stoch: bad value %q\n", a)
+			return
+		}
+		switch kv[0] {
+		case "temp":
+			ctx.Temperature = v
+		case "explore":
+			ctx.ExploreRate = v
+		case "drop":
+			ctx.NoiseDrop = v
+		case "swap":
+			ctx.NoiseSwap = v
+		case "subst":
+			ctx.NoiseSubst = v
+		default:
+			fmt.Printf("stoch: unknown key %q\n", kv[0])
+			return
+		}
+		journalf(ctx, "STOCH %s=%.2f", kv[0], v)
+	}
+	cmdSeed(ctx, nil)
+}
+
+// cmdJournal handles: journal [n]
+func cmdJournal(ctx *Context, args []string) {
+	n := 20
+	if len(args) > 0 {
+		if v, err := strconv.Atoi(args[0]); err == nil && v > 0 {
+			n = v
+		}
+	}
+	from := len(ctx.Journal) - n
+	if from < 0 {
+		from = 0
+	}
+	if len(ctx.Journal) == 0 {
+		fmt.Println("Journal: (empty)")
+	}
+	for _, e := range ctx.Journal[from:] {
+		fmt.Printf("JOURNAL %s\n", e)
+	}
+}
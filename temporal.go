@@ -0,0 +1,587 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// TimedTok is one sensory token with the tick it arrived at.
+type TimedTok struct {
+	Tick int
+	Tok  string
+}
+
+// TimedExpect is an expectation armed for a specific future tick.
+// It is met when Token arrives within Tol ticks of Due, and violated
+// once Due+Tol has passed without it.
+type TimedExpect struct {
+	Struct  string
+	Token   string
+	Due     int
+	Tol     int
+	Conf    float64
+	Step    int // position in a multi-step chain (0 for gap/rhythm expectations)
+	ArmedAt int // tick the chain was armed
+}
+
+// timedLearner is implemented by blocks that arm timed expectations and
+// want to hear whether they were met.
+type timedLearner interface {
+	timedOutcome(hit bool)
+	collapsed() bool
+	retire(ctx *Context)
+}
+
+// noInput stands for "nothing was sensed" in timed ERR values. It contains a
+// space, so it can never be a sensed token (such as the silence token).
+const noInput = "<no input>"
+
+const (
+	gapMinCount   = 3    // consistent occurrences a gap needs before it is learned
+	gapMinRatio   = 0.8  // share of a's occurrences that must be followed by b at the gap
+	timedMinTries = 6    // outcomes a timed block needs before it can be retired
+	timedRateStep = 0.25 // weight of the latest outcome in the recent hit rate
+	timedRetire   = 0.3  // recent hit rate below which it is retired
+)
+
+// recentRate folds one outcome into an exponentially weighted hit rate.
+func recentRate(rate float64, hit bool) float64 {
+	v := 0.0
+	if hit {
+		v = 1
+	}
+	return rate + timedRateStep*(v-rate)
+}
+
+// hitRateCollapsed reports whether a timed block has recently missed too often.
+func hitRateCollapsed(hits, misses, rate float64) bool {
+	return hits+misses >= timedMinTries && rate < timedRetire
+}
+
+// ArmAt arms an expectation of tok at tick due (±tol) on behalf of structure st.
+// An existing expectation of the same structure for the same tick is replaced.
+func (c *Context) ArmAt(st, tok string, due, tol int, conf float64) {
+	c.armTimed(TimedExpect{Struct: st, Token: tok, Due: due, Tol: tol, Conf: conf, ArmedAt: c.Tick})
+}
+
+func (c *Context) armTimed(te TimedExpect) {
+	for i, old := range c.TimedExpect {
+		if old.Struct == te.Struct && old.Due == te.Due {
+			c.TimedExpect[i] = te
+			return
+		}
+	}
+	c.TimedExpect = append(c.TimedExpect, te)
+}
+
+// armHorizon extends the winner's next-tick expectation into a chain of
+// ExpectHorizon steps. Each step asks the structure that the expected
+// continuation would activate (the pair, else the sequence, of the last two
+// tokens) for its prediction. Confidence multiplies along the chain and
+// decays by HorizonDecay per step; the chain stops when it fades out.
+func armHorizon(ctx *Context, winner string) {
+	if ctx.ExpectHorizon < 2 || winner == "" {
+		return
+	}
+	cur := ctx.ThisExpect[winner]
+	if cur == "" || ctx.LastSens == "" {
+		return
+	}
+	prev := ctx.LastSens
+	conf := ctx.PredConf[winner]
+
+	for k := 2; k <= ctx.ExpectHorizon; k++ {
+		st := chainStruct(ctx, prev, cur)
+		if st == "" {
+			return
+		}
+		next := ctx.BestPred[st]
+		conf *= ctx.PredConf[st] * ctx.HorizonDecay
+		if next == "" || conf < 0.05 {
+			return
+		}
+		ctx.armTimed(TimedExpect{
+			Struct:  winner,
+			Token:   next,
+			Due:     ctx.Tick + k,
+			Conf:    conf,
+			Step:    k,
+			ArmedAt: ctx.Tick,
+		})
+		prev, cur = cur, next
+	}
+}
+
+// chainStruct names the learned structure that the transition a -> b activates.
+func chainStruct(ctx *Context, a, b string) string {
+	if a == b {
+		return ""
+	}
+	if pn := canonicalPairName(a, b); ctx.BestPred[pn] != "" {
+		if _, ok := ctx.Blocks["COACT:"+pn]; ok {
+			return pn
+		}
+	}
+	if sn := fmt.Sprintf("(%s>%s)", a, b); ctx.BestPred[sn] != "" {
+		if _, ok := ctx.Blocks["SEQ:"+sn]; ok {
+			return sn
+		}
+	}
+	return ""
+}
+
+// checkTimedExpectations resolves timed expectations against this tick's input.
+// Met expectations are dropped; expired ones produce ERR signals.
+func checkTimedExpectations(ctx *Context, actualByChan map[string][]string) []Signal {
+	if len(ctx.TimedExpect) == 0 {
+		return nil
+	}
+
+	errs := make([]Signal, 0, 2)
+	dropped := make(map[chainKey]bool)
+	retired := make(map[string]bool)
+	keep := ctx.TimedExpect[:0]
+	for _, te := range ctx.TimedExpect {
+		if retired[te.Struct] {
+			continue
+		}
+		ch := chanOf(te.Token)
+		got := actualByChan[ch]
+
+		if containsStr(got, te.Token) && ctx.Tick >= te.Due-te.Tol && ctx.Tick <= te.Due+te.Tol {
+			if te.Step == 0 {
+				notifyTimed(ctx, te.Struct, true)
+			}
+			continue
+		}
+		if ctx.Tick < te.Due+te.Tol {
+			keep = append(keep, te)
+			continue
+		}
+
+		actual := noInput
+		if te.Due < ctx.Tick {
+			actual = sensedOn(ctx, ch, te.Due)
+		} else if len(got) > 0 {
+			actual = got[0]
+		}
+		if te.Step > 0 {
+			dropped[chainKey{te.Struct, te.ArmedAt}] = true
+		}
+		errs = append(errs, Signal{
+			Kind:  K_ERR,
+			Value: fmt.Sprintf("%s:%s->%s", te.Struct, te.Token, actual),
+			Mass:  1.0,
+			Time:  ctx.Tick,
+			From:  "FIELD:TIMED",
+		})
+		ctx.Inhib[te.Struct] += 0.08
+		if te.Step == 0 && notifyTimed(ctx, te.Struct, false) {
+			retired[te.Struct] = true
+		}
+		if ctx.PredEvents != nil {
+			if te.Step > 0 {
+				ctx.PredEvents = append(ctx.PredEvents,
+					fmt.Sprintf("EARLY-WARN %s step %d expected %s at t=%d got %s (conf=%.2f)",
+						te.Struct, te.Step, te.Token, te.Due, actual, te.Conf))
+			} else {
+				ctx.PredEvents = append(ctx.PredEvents,
+					fmt.Sprintf("ERR %s expected %s at t=%d got %s", te.Struct, te.Token, te.Due, actual))
+			}
+		}
+	}
+
+	// Once a chain is off track, its later steps are dropped, and so are
+	// the pending expectations of retired blocks.
+	if len(dropped) > 0 || len(retired) > 0 {
+		rest := keep[:0]
+		for _, te := range keep {
+			if retired[te.Struct] || (te.Step > 0 && dropped[chainKey{te.Struct, te.ArmedAt}]) {
+				continue
+			}
+			rest = append(rest, te)
+		}
+		keep = rest
+	}
+	ctx.TimedExpect = keep
+	return errs
+}
+
+type chainKey struct {
+	st    string
+	armed int
+}
+
+// notifyTimed reports an outcome to the block behind st and retires the
+// block once its hit rate has collapsed; it reports whether it did.
+func notifyTimed(ctx *Context, st string, hit bool) bool {
+	retired := false
+	for _, prefix := range []string{"GAP:", "RHYTHM:"} {
+		tl, ok := ctx.Blocks[prefix+st].(timedLearner)
+		if !ok {
+			continue
+		}
+		tl.timedOutcome(hit)
+		if !hit && tl.collapsed() && !ctx.Pinned[st] {
+			tl.retire(ctx)
+			removeBlock(ctx, prefix+st)
+			ctx.TrainEvents = append(ctx.TrainEvents, fmt.Sprintf("--- RETIRED %s%s (hit rate collapsed)", prefix, st))
+			retired = true
+		}
+	}
+	return retired
+}
+
+// sensedOn returns the token sensed on channel ch at tick t, or noInput.
+func sensedOn(ctx *Context, ch string, t int) string {
+	for _, e := range ctx.SensLog {
+		if e.Tick == t && chanOf(e.Tok) == ch {
+			return e.Tok
+		}
+	}
+	return noInput
+}
+
+// sensAt reports whether tok was sensed at a tick in [from, to].
+func sensAt(ctx *Context, tok string, from, to int) bool {
+	for i := len(ctx.SensLog) - 1; i >= 0; i-- {
+		e := ctx.SensLog[i]
+		if e.Tick < from {
+			break
+		}
+		if e.Tick <= to && e.Tok == tok {
+			return true
+		}
+	}
+	return false
+}
+
+// GapSeqBlock learns "a then b after gap ticks" (± tol).
+// Once mature it arms an expectation of b when a arrives, and emits its
+// structure when b completes the pattern.
+type GapSeqBlock struct {
+	a, b         string
+	gap, tol     int
+	name         string
+	hits, misses float64
+	rate         float64 // recent hit rate
+}
+
+func NewGapSeqBlock(a, b string, gap, tol int) *GapSeqBlock {
+	return &GapSeqBlock{
+		a:    a,
+		b:    b,
+		gap:  gap,
+		tol:  tol,
+		name: fmt.Sprintf("(%s>%s@%d)", a, b, gap),
+		rate: 1,
+	}
+}
+
+func (b *GapSeqBlock) ID() string { return "GAP:" + b.name }
+
+func (b *GapSeqBlock) Clone() Block { c := *b; return &c }
+
+// conf is a smoothed hit rate of the armed expectations.
+func (b *GapSeqBlock) conf() float64 { return (b.hits + 1) / (b.hits + b.misses + 2) }
+
+func (b *GapSeqBlock) timedOutcome(hit bool) {
+	b.rate = recentRate(b.rate, hit)
+	if hit {
+		b.hits++
+	} else {
+		b.misses++
+	}
+}
+
+func (b *GapSeqBlock) collapsed() bool { return hitRateCollapsed(b.hits, b.misses, b.rate) }
+
+// retire drops the gap's evidence so it has to be earned again.
+func (b *GapSeqBlock) retire(ctx *Context) {
+	delete(ctx.SeenGaps, gapKey(b.a, b.b, b.gap))
+}
+
+func gapKey(a, b string, gap int) string { return fmt.Sprintf("%s>%s@%d", a, b, gap) }
+
+func (b *GapSeqBlock) React(s Signal, ctx *Context) []Signal {
+	if s.Kind != K_ACT {
+		return nil
+	}
+
+	if s.Value == b.a {
+		ctx.BlockLastFire[b.ID()] = ctx.Tick
+		ctx.ArmAt(b.name, b.b, ctx.Tick+b.gap, b.tol, b.conf())
+	}
+
+	if s.Value == b.b && sensAt(ctx, b.a, ctx.Tick-b.gap-b.tol, ctx.Tick-b.gap+b.tol) {
+		ctx.BlockLastFire[b.ID()] = ctx.Tick
+		return []Signal{{
+			Kind:  K_STRUCT,
+			Value: b.name,
+			Mass:  b.conf(),
+			Time:  ctx.Tick,
+			From:  b.ID(),
+		}}
+	}
+	return nil
+}
+
+func (b *GapSeqBlock) Tick(ctx *Context) []Signal { return nil }
+
+// RhythmBlock detects a token recurring every period ticks (± tol).
+// While the rhythm holds it emits its structure and arms the next beat.
+type RhythmBlock struct {
+	tok          string
+	period, tol  int
+	name         string
+	last         int
+	streak       int
+	hits, misses float64
+	rate         float64 // recent hit rate
+}
+
+func NewRhythmBlock(tok string, period, tol int) *RhythmBlock {
+	return &RhythmBlock{
+		tok:    tok,
+		period: period,
+		tol:    tol,
+		name:   fmt.Sprintf("~%s/%d", tok, period),
+		last:   -1,
+		rate:   1,
+	}
+}
+
+func (b *RhythmBlock) ID() string { return "RHYTHM:" + b.name }
+
+func (b *RhythmBlock) Clone() Block { c := *b; return &c }
+
+func (b *RhythmBlock) conf() float64 { return (b.hits + 1) / (b.hits + b.misses + 2) }
+
+func (b *RhythmBlock) timedOutcome(hit bool) {
+	b.rate = recentRate(b.rate, hit)
+	if hit {
+		b.hits++
+	} else {
+		b.misses++
+		b.streak = 0
+	}
+}
+
+func (b *RhythmBlock) collapsed() bool { return hitRateCollapsed(b.hits, b.misses, b.rate) }
+
+// retire restarts the interval count, so the rhythm has to recur again.
+func (b *RhythmBlock) retire(ctx *Context) {
+	ctx.IntervalRun[b.tok] = 0
+}
+
+func (b *RhythmBlock) React(s Signal, ctx *Context) []Signal {
+	if s.Kind != K_ACT || s.Value != b.tok || b.last == ctx.Tick {
+		return nil
+	}
+
+	interval := ctx.Tick - b.last
+	if b.last >= 0 && interval >= b.period-b.tol && interval <= b.period+b.tol {
+		b.streak++
+	} else {
+		b.streak = 0
+	}
+	b.last = ctx.Tick
+
+	if b.streak == 0 {
+		return nil
+	}
+	ctx.BlockLastFire[b.ID()] = ctx.Tick
+	ctx.ArmAt(b.name, b.tok, ctx.Tick+b.period, b.tol, b.conf())
+	return []Signal{{
+		Kind:  K_STRUCT,
+		Value: b.name,
+		Mass:  b.conf(),
+		Time:  ctx.Tick,
+		From:  b.ID(),
+	}}
+}
+
+func (b *RhythmBlock) Tick(ctx *Context) []Signal { return nil }
+
+// learnTemporal accumulates evidence for variable-gap transitions and rhythms
+// from the sensory log and crystallizes GapSeqBlocks and RhythmBlocks.
+//
+// For every token sensed now, only the most recent earlier occurrence of each
+// other token is considered, at gaps 2..MaxGap (gap 1 is plain adjacency).
+// A gap is learned once b followed a at that gap gapMinCount times and in at
+// least gapMinRatio of a's occurrences. A rhythm forms once a token recurs
+// with the same interval three times. Both are opt-in: MaxGap < 2 disables
+// temporal learning altogether.
+func learnTemporal(ctx *Context) {
+	if ctx.MaxGap < 2 {
+		return
+	}
+	learnGaps(ctx)
+	learnRhythms(ctx)
+}
+
+func learnGaps(ctx *Context) {
+	for _, b := range ctx.SensNow {
+		seen := make(map[string]bool, 8)
+		for i := len(ctx.SensLog) - 1; i >= 0; i-- {
+			e := ctx.SensLog[i]
+			if e.Tick >= ctx.Tick {
+				continue
+			}
+			gap := ctx.Tick - e.Tick
+			if gap > ctx.MaxGap {
+				break
+			}
+			if seen[e.Tok] {
+				continue
+			}
+			seen[e.Tok] = true
+			if gap < 2 || e.Tok == b {
+				continue
+			}
+
+			gk := gapKey(e.Tok, b, gap)
+			if v, ok := ctx.SeenGaps[gk]; ok && v < 0 {
+				continue
+			}
+			ctx.SeenGaps[gk]++
+			n := ctx.SeenGaps[gk]
+			if n >= gapMinCount && n >= gapMinRatio*float64(gapChances(ctx, e.Tok, gap)) {
+				blk := NewGapSeqBlock(e.Tok, b, gap, ctx.GapTol)
+				if _, exists := ctx.Blocks[blk.ID()]; !exists {
+					ctx.AddBlock(blk)
+					ctx.TrainEvents = append(ctx.TrainEvents, fmt.Sprintf("+++ LEARNED NEW GAP BLOCK %s", blk.name))
+				}
+				ctx.SeenGaps[gk] = -1.0
+			}
+		}
+	}
+	for _, a := range ctx.SensNow {
+		ctx.GapBase[a]++
+	}
+}
+
+// gapChances counts the earlier occurrences of a that are at least gap ticks
+// old, i.e. that could have been followed by something at that gap by now.
+func gapChances(ctx *Context, a string, gap int) int {
+	n := ctx.GapBase[a]
+	for i := len(ctx.SensLog) - 1; i >= 0 && ctx.SensLog[i].Tick > ctx.Tick-gap; i-- {
+		if e := ctx.SensLog[i]; e.Tok == a && e.Tick < ctx.Tick {
+			n--
+		}
+	}
+	return n
+}
+
+func learnRhythms(ctx *Context) {
+	for _, tok := range ctx.SensNow {
+		last, ok := ctx.LastSeenAt[tok]
+		ctx.LastSeenAt[tok] = ctx.Tick
+		if !ok {
+			continue
+		}
+		interval := ctx.Tick - last
+		if interval < 2 {
+			ctx.IntervalRun[tok] = 0
+			continue
+		}
+		if prev := ctx.LastInterval[tok]; prev > 0 && absInt(interval-prev) <= ctx.GapTol {
+			ctx.IntervalRun[tok]++
+		} else {
+			ctx.IntervalRun[tok] = 0
+		}
+		ctx.LastInterval[tok] = interval
+
+		if ctx.IntervalRun[tok] >= 2 {
+			blk := NewRhythmBlock(tok, interval, ctx.GapTol)
+			if _, exists := ctx.Blocks[blk.ID()]; !exists {
+				blk.last = ctx.Tick
+				ctx.AddBlock(blk)
+				ctx.TrainEvents = append(ctx.TrainEvents, fmt.Sprintf("+++ LEARNED NEW RHYTHM BLOCK %s", blk.name))
+			}
+		}
+	}
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// appendSensLog records this tick's tokens and trims the log to TemporalWindow ticks.
+func appendSensLog(ctx *Context) {
+	for _, tok := range ctx.SensNow {
+		ctx.SensLog = append(ctx.SensLog, TimedTok{Tick: ctx.Tick, Tok: tok})
+	}
+	cut := 0
+	for cut < len(ctx.SensLog) && ctx.SensLog[cut].Tick < ctx.Tick-ctx.TemporalWindow {
+		cut++
+	}
+	if cut > 0 {
+		ctx.SensLog = append(ctx.SensLog[:0], ctx.SensLog[cut:]...)
+	}
+}
+
+// timedExpectations lists pending timed expectations for the board.
+func timedExpectations(ctx *Context, n int) []string {
+	tes := append([]TimedExpect(nil), ctx.TimedExpect...)
+	sort.Slice(tes, func(i, j int) bool {
+		if tes[i].Due != tes[j].Due {
+			return tes[i].Due < tes[j].Due
+		}
+		return tes[i].Struct < tes[j].Struct
+	})
+	out := make([]string, 0, n)
+	for _, te := range tes {
+		if len(out) >= n {
+			break
+		}
+		out = append(out, fmt.Sprintf("%s⇒%s@t=%d±%d(%.2f)", te.Struct, te.Token, te.Due, te.Tol, te.Conf))
+	}
+	return out
+}
+
+// cmdHorizon handles: horizon [n] [decay]
+func cmdHorizon(ctx *Context, args []string) {
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			fmt.Printf("horizon: bad step count %q\n", args[0])
+			return
+		}
+		ctx.ExpectHorizon = n
+	}
+	if len(args) > 1 {
+		d, err := strconv.ParseFloat(args[1], 64)
+		if err != nil || d <= 0 || d > 1 {
+			fmt.Printf("horizon: bad decay %q\n", args[1])
+			return
+		}
+		ctx.HorizonDecay = d
+	}
+	fmt.Printf("Expectation horizon = %d steps (decay=%.2f)\n", ctx.ExpectHorizon, ctx.HorizonDecay)
+}
+
+// cmdGap handles: gap [maxgap] [tol]
+func cmdGap(ctx *Context, args []string) {
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 || n > ctx.TemporalWindow {
+			fmt.Printf("gap: bad max gap %q (0..%d)\n", args[0], ctx.TemporalWindow)
+			return
+		}
+		ctx.MaxGap = n
+	}
+	if len(args) > 1 {
+		t, err := strconv.Atoi(args[1])
+		if err != nil || t < 0 {
+			fmt.Printf("gap: bad tolerance %q\n", args[1])
+			return
+		}
+		ctx.GapTol = t
+	}
+	fmt.Printf("Gap and rhythm learning: max gap = %d (0 = off), tolerance = %d\n", ctx.MaxGap, ctx.GapTol)
+}
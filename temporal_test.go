@@ -0,0 +1,146 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// trainCycle feeds the tokens round and round with learning on.
+func trainCycle(ctx *Context, toks []string, reps int) {
+	for i := 0; i < reps; i++ {
+		for _, tok := range toks {
+			feedToken(ctx, tok)
+		}
+	}
+}
+
+func TestHorizonChainsExpectations(t *testing.T) {
+	ctx := NewContext()
+	trainCycle(ctx, []string{"1", "2", "3", "4", "5"}, 12)
+	ctx.ExpectHorizon = 3
+	ctx.TimedExpect = nil
+	feedToken(ctx, "1")
+	feedToken(ctx, "2")
+
+	want := map[int]string{2: "4", 3: "5"}
+	for _, te := range ctx.TimedExpect {
+		if te.Step == 0 || te.ArmedAt != ctx.Tick {
+			continue
+		}
+		if want[te.Step] != te.Token || te.Due != ctx.Tick+te.Step {
+			t.Errorf("step %d expects %s at t=%d, want %s at t=%d", te.Step, te.Token, te.Due, want[te.Step], ctx.Tick+te.Step)
+		}
+		delete(want, te.Step)
+	}
+	if len(want) > 0 {
+		t.Errorf("steps %v not armed: %v", want, ctx.TimedExpect)
+	}
+}
+
+func TestHorizonEarlyWarning(t *testing.T) {
+	ctx := NewContext()
+	trainCycle(ctx, []string{"1", "2", "3", "4", "5"}, 12)
+	ctx.ExpectHorizon = 3
+	ctx.LearningEnabled = false
+	ctx.TimedExpect = nil
+	ctx.PredEvents = make([]string, 0, 8)
+	for _, tok := range []string{"1", "2", "3", "9"} {
+		feedToken(ctx, tok)
+	}
+
+	warned := false
+	for _, ev := range ctx.PredEvents {
+		warned = warned || strings.HasPrefix(ev, "EARLY-WARN")
+	}
+	if !warned {
+		t.Errorf("no early warning in %v", ctx.PredEvents)
+	}
+	for _, te := range ctx.TimedExpect {
+		if te.Step > 0 && te.ArmedAt < ctx.Tick-1 {
+			t.Errorf("step %d of the broken chain armed at t=%d still pending", te.Step, te.ArmedAt)
+		}
+	}
+}
+
+func TestTemporalLearningOptIn(t *testing.T) {
+	for _, maxGap := range []int{0, 6} {
+		ctx := NewContext()
+		ctx.MaxGap = maxGap
+		trainCycle(ctx, []string{"1", "2"}, 6)
+		n := countBlocksByPrefix(ctx, "RHYTHM:") + countBlocksByPrefix(ctx, "GAP:")
+		if (n > 0) != (maxGap > 0) {
+			t.Errorf("max gap %d: %d gap/rhythm blocks", maxGap, n)
+		}
+	}
+}
+
+func TestGapLearnedByRatio(t *testing.T) {
+	ctx := NewContext()
+	ctx.MaxGap = 4
+	// 1 is followed by 2 two ticks later every time, by 3 only every other time.
+	trainCycle(ctx, []string{"1", "5", "2", "3", "1", "6", "2", "7"}, 4)
+
+	if _, ok := ctx.Blocks["GAP:(1>2@2)"]; !ok {
+		t.Errorf("consistent gap (1>2@2) not learned: %v", sortedKeys(ctx.Blocks))
+	}
+	if _, ok := ctx.Blocks["GAP:(1>3@3)"]; ok {
+		t.Errorf("gap (1>3@3) learned from every other occurrence")
+	}
+}
+
+func TestCollapsedGapRetires(t *testing.T) {
+	ctx := NewContext()
+	ctx.MaxGap = 4
+	trainCycle(ctx, []string{"1", "5", "2", "6"}, 5)
+	if _, ok := ctx.Blocks["GAP:(1>2@2)"]; !ok {
+		t.Fatal("gap (1>2@2) not learned")
+	}
+	trainCycle(ctx, []string{"1", "5", "7", "6"}, 8)
+
+	if _, ok := ctx.Blocks["GAP:(1>2@2)"]; ok {
+		t.Errorf("gap kept after its pattern broke")
+	}
+	retired := false
+	for _, ev := range ctx.TrainEvents {
+		retired = retired || strings.HasPrefix(ev, "--- RETIRED GAP:(1>2@2)")
+	}
+	if !retired {
+		t.Errorf("no retirement event")
+	}
+}
+
+func TestTimedErrorReportsActualInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		tol    int
+		atDue  string // token sensed at the due tick ("" = none)
+		atEnd  string // token sensed when the window closes ("" = none)
+		actual string
+	}{
+		{"nothing", 0, "", "", noInput},
+		{"silence token", 0, "_", "_", "_"},
+		{"other token", 0, "9", "9", "9"},
+		{"nothing at due", 1, "", "9", noInput},
+		{"silence at due", 1, "_", "9", "_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			ctx.ArmAt("(1>2@2)", "2", 5, tt.tol, 0.8)
+			ctx.Tick = 5 + tt.tol
+			if tt.atDue != "" {
+				ctx.SensLog = append(ctx.SensLog, TimedTok{Tick: 5, Tok: tt.atDue})
+			}
+			now := map[string][]string{}
+			if tt.atEnd != "" {
+				now[chanOf(tt.atEnd)] = []string{tt.atEnd}
+			}
+
+			errs := checkTimedExpectations(ctx, now)
+			want := "(1>2@2):2->" + tt.actual
+			if len(errs) != 1 || errs[0].Value != want {
+				t.Errorf("errors = %v, want %s", errs, want)
+			}
+		})
+	}
+}